@@ -4,11 +4,24 @@ import "net/http"
 
 // checkHandler распределяет запросы эндпойнта "/api/check" по типу
 // в данном случае у нас только POST
-func checkHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) checkHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodPost:
-		CheckPostHandler(w, r)
+		h.CheckPostHandler(w, r)
+
+	default:
+		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// checkJobHandler распределяет запросы эндпойнта "/api/check/{links_num}" по типу
+// в данном случае у нас только GET
+func (h *Handlers) checkJobHandler(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodGet:
+		h.CheckGetHandler(w, r)
 
 	default:
 		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -0,0 +1,46 @@
+package api
+
+import "net/http"
+
+// monitorsHandler распределяет запросы эндпойнта "/api/monitors" по типу
+func (h *Handlers) monitorsHandler(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodPost:
+		h.MonitorPostHandler(w, r)
+
+	case http.MethodGet:
+		h.MonitorListHandler(w, r)
+
+	default:
+		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// monitorHandler распределяет запросы эндпойнта "/api/monitors/{id}" по типу
+func (h *Handlers) monitorHandler(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodGet:
+		h.MonitorGetHandler(w, r)
+
+	case http.MethodDelete:
+		h.MonitorDeleteHandler(w, r)
+
+	default:
+		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// monitorRunsHandler распределяет запросы эндпойнта "/api/monitors/{id}/runs" по типу
+// в данном случае у нас только GET
+func (h *Handlers) monitorRunsHandler(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodGet:
+		h.MonitorRunsHandler(w, r)
+
+	default:
+		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
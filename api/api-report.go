@@ -4,11 +4,11 @@ import "net/http"
 
 // reportHandler распределяет запросы эндпойнта "/api/report" по типу
 // в данном случае у нас только POST
-func reportHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) reportHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodPost:
-		ReportPostHandler(w, r)
+		h.ReportPostHandler(w, r)
 
 	default:
 		WriterJSON(w, http.StatusMethodNotAllowed, "Method not allowed")
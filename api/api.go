@@ -1,10 +1,57 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+	"time"
 
-func Init() {
+	"verifi-server/checker"
+	"verifi-server/data"
+)
 
-	http.HandleFunc("/api/check", checkHandler)
+// Handlers обработчики api с внедрённым хранилищем результатов и движком проверок
+type Handlers struct {
+	store     data.Store
+	engine    *checker.Engine
+	jobs      jobRegistry       // незавершённые асинхронные проверки
+	monitors  data.MonitorStore // мониторы и история их прогонов
+	scheduler monitorScheduler  // прогоны мониторов по расписанию
+}
+
+// NewHandlers создаёт обработчики поверх переданных хранилища и движка проверок
+func NewHandlers(store data.Store, engine *checker.Engine) *Handlers {
+
+	return &Handlers{
+		store:  store,
+		engine: engine,
+		jobs:   jobRegistry{jobs: make(map[int]*checkJob)},
+
+		// пока не подключено другое хранилище (StartMonitors), мониторы живут в памяти
+		monitors: data.NewMonitorStorage(),
+		scheduler: monitorScheduler{
+			stops: make(map[int]chan struct{}),
+			next:  make(map[int]time.Time),
+		},
+	}
+}
+
+// Init регистрирует эндпойнты api с хранилищем store и движком engine,
+// ставит в расписание мониторы из monitors и возвращает обработчики
+func Init(store data.Store, monitors data.MonitorStore, engine *checker.Engine) *Handlers {
+
+	h := NewHandlers(store, engine)
+	h.StartMonitors(monitors)
+
+	http.HandleFunc("/api/check", h.checkHandler)
+
+	http.HandleFunc("/api/check/{links_num}", h.checkJobHandler)
+
+	http.HandleFunc("/api/report", h.reportHandler)
+
+	http.HandleFunc("/api/monitors", h.monitorsHandler)
+
+	http.HandleFunc("/api/monitors/{id}", h.monitorHandler)
+
+	http.HandleFunc("/api/monitors/{id}/runs", h.monitorRunsHandler)
 
-	http.HandleFunc("/api/report", reportHandler)
+	return h
 }
@@ -3,42 +3,186 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
-	"strings"
+	"slices"
 	"time"
 
+	"verifi-server/checker"
 	"verifi-server/data"
 	"verifi-server/server"
 )
 
 const (
-	// время на запрос клиенту
-	timeoutClient = 3
-	// константы статусов на выдачу
-	AvailableStatus    = "available"
-	NotAvailableStatus = "not available"
-)
+	// константы статусов на выдачу (прежняя модель)
+	AvailableStatus    = data.AvailableStatus
+	NotAvailableStatus = data.NotAvailableStatus
 
-// Link описывает структуру обрабатывемой ссылки
-type Link struct {
-	Url    string // адрес
-	Status string // статус ресурса по адресу
-}
+	// состояния ресурса
+	UpStatus       = data.UpStatus
+	DegradedStatus = data.DegradedStatus
+	DownStatus     = data.DownStatus
+	UnknownStatus  = data.UnknownStatus
+	BlockedStatus  = data.BlockedStatus
+	InvalidStatus  = data.InvalidStatus
+)
 
 // RequestLinks структура запроса от клиента со ссылками
 type RequestLinks struct {
-	Links []string `json:"links"`
+	Links    []data.Link  `json:"links"`            // адреса строками или объектами с собственными правилами
+	Policy   *data.Policy `json:"policy,omitempty"` // общие правила проверки для всех ссылок
+	Detailed bool         `json:"detailed"`         // вернуть подробные результаты проверки
+
+	Proxy         string `json:"proxy,omitempty"`          // прокси для всех ссылок запроса ("direct" - напрямую)
+	CompareDirect bool   `json:"compare_direct,omitempty"` // проверять ссылки ещё и напрямую, чтобы сравнить
+	TLSProfile    string `json:"tls_profile,omitempty"`    // профиль TLS для всех ссылок запроса
+}
+
+// targets ссылки запроса с общими правилами, поверх которых наложены правила каждой ссылки;
+// прокси ссылки важнее прокси запроса
+func (req RequestLinks) targets() []data.Link {
+
+	var global data.Policy
+	if req.Policy != nil {
+		global = *req.Policy
+	}
+
+	links := make([]data.Link, len(req.Links))
+	for i, link := range req.Links {
+		policy := global.Merge(link.Policy)
+		links[i] = link
+		links[i].Policy = nil
+		if !policy.IsZero() {
+			links[i].Policy = &policy
+		}
+		if link.Proxy == "" {
+			links[i].Proxy = req.Proxy
+		}
+		links[i].CompareDirect = link.CompareDirect || req.CompareDirect
+		if link.TLSProfile == "" {
+			links[i].TLSProfile = req.TLSProfile
+		}
+	}
+
+	return links
+}
+
+// normalize приводит адреса ссылок к единому виду и убирает повторы (остаётся первая ссылка);
+// адреса, которые не могут быть ссылками, убираются из запроса с причиной в замечаниях,
+// а для прежнего поля links запоминается, под какими адресами ссылка пришла в запросе
+func (req *RequestLinks) normalize() LinkNotes {
+
+	notes := LinkNotes{origins: make(map[string][]string, len(req.Links))}
+
+	seen := make(map[string]bool, len(req.Links))
+	links := make([]data.Link, 0, len(req.Links))
+	for _, link := range req.Links {
+		// в замечаниях адреса без паролей и токенов, как и в результатах
+		url, err := data.NormalizeURL(link.Url)
+		if err != nil {
+			if notes.Invalid == nil {
+				notes.Invalid = make(map[string]string)
+			}
+			notes.Invalid[data.RedactURL(link.Url)] = err.Error()
+			continue
+		}
+
+		if url != link.Url {
+			if notes.Normalized == nil {
+				notes.Normalized = make(map[string]string)
+			}
+			notes.Normalized[data.RedactURL(link.Url)] = data.RedactURL(url)
+		}
+
+		key := resultURL(link, url)
+		if origin := resultURL(link, link.Url); !slices.Contains(notes.origins[key], origin) {
+			notes.origins[key] = append(notes.origins[key], origin)
+		}
+
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		link.Url = url
+		links = append(links, link)
+	}
+	req.Links = links
+
+	return notes
+}
+
+// resultURL адрес ссылки в том виде, в каком он станет ключом результатов (без секретов)
+func resultURL(link data.Link, url string) string {
+
+	res := data.CheckResult{Url: url}
+	res.Redact(link.Secrets())
+
+	return res.Url
+}
+
+// LinkNotes замечания к адресам запроса
+type LinkNotes struct {
+	Invalid    map[string]string `json:"invalid,omitempty"`    // map [{url: причина}] адреса, которые не проверялись
+	Normalized map[string]string `json:"normalized,omitempty"` // map [{url из запроса: url в результатах}] изменённые адреса
+
+	origins map[string][]string // map [url в результатах] адреса из запроса
+}
+
+// invalidResults результаты для адресов, которые не могут быть ссылками: они не проверялись,
+// но попадают в набор с состоянием invalid, как и ссылки, отклонённые при проверке
+func (n LinkNotes) invalidResults() map[string]data.CheckResult {
+
+	results := make(map[string]data.CheckResult, len(n.Invalid))
+	for url, reason := range n.Invalid {
+		res := data.CheckResult{
+			Url:       url,
+			ErrorKind: data.ErrorKindInvalid,
+			Error:     reason,
+			CheckedAt: time.Now(),
+		}
+		res.Settle()
+		results[url] = res
+	}
+
+	return results
+}
+
+// withOrigins дописывает к результату адреса, под которыми ссылка пришла в запросе, если они
+// отличаются от адреса результата: они сохраняются с набором, и прежнее поле links ключуется
+// ими во всех ответах - синхронном, асинхронном, потоковом и по номеру набора
+func (n LinkNotes) withOrigins(res data.CheckResult) data.CheckResult {
+
+	if origins := n.origins[res.Url]; len(origins) != 0 && !slices.Equal(origins, []string{res.Url}) {
+		res.Origins = origins
+	}
+
+	return res
+}
+
+// withOriginsAll то же для всех результатов набора
+func (n LinkNotes) withOriginsAll(results map[string]data.CheckResult) {
+
+	for url, res := range results {
+		results[url] = n.withOrigins(res)
+	}
 }
 
 // ResponseLinks структура ответа по запросу со ссылками
 type ResponseLinks struct {
-	Links    map[string]string `json:"links"`     // map [{url: status}]
-	LinksNum int               `json:"links_num"` // номер набора
+	Links    map[string]string           `json:"links"`             // map [{url из запроса: status}]
+	States   map[string]data.Status      `json:"states"`            // map [{url: state}]
+	LinksNum int                         `json:"links_num"`         // номер набора
+	Results  map[string]data.CheckResult `json:"results,omitempty"` // map [{url: result}] при detailed
+	LinkNotes
 }
 
 // CheckPostHandler принимает запрос с адресами и синхронно собирает статусы
-func CheckPostHandler(w http.ResponseWriter, r *http.Request) {
+// (с параметром ?async=true - запускает проверку в фоне и сразу возвращает номер,
+// с ?stream=true или Accept: application/x-ndjson, text/event-stream - отдаёт результаты потоком)
+func (h *Handlers) CheckPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req RequestLinks
 	var buf bytes.Buffer
@@ -50,16 +194,21 @@ func CheckPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// десериализуем запрос клиента в []string
+	// десериализуем запрос клиента
 	err = json.Unmarshal(buf.Bytes(), &req)
 	if err != nil {
 		WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("невозможно десериализовать тело запроса %v", err.Error()))
 		return
 	}
 
-	// проверяем на всякий случай
-	if len(req.Links) == 0 {
-		WriterJSON(w, http.StatusBadRequest, "ссылок нет")
+	// приводим адреса к единому виду и проверяем правила ссылок
+	links, notes, err := h.prepareLinks(&req)
+	if errors.Is(err, errNoValidLinks) {
+		WriterJSON(w, http.StatusBadRequest, notes)
+		return
+	}
+	if err != nil {
+		WriterJSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -67,100 +216,166 @@ func CheckPostHandler(w http.ResponseWriter, r *http.Request) {
 	// записываем поступающие текущие запросы-ссылки в ShutdownCache
 	// и заканчиваем соединение
 	if server.IsShutdown() {
-		data.SaveLinksCache(req.Links)
+		data.SaveLinksCache(links)
 		WriterJSON(w, http.StatusServiceUnavailable, "сервис недоступен - повторите запрос позднее")
 		return
 	}
 
+	// в асинхронном режиме сразу отдаём номер, а проверки идут в фоне
+	if r.URL.Query().Get("async") == "true" {
+		job, err := h.startJob(links, notes)
+		if err != nil {
+			WriterJSON(w, http.StatusInternalServerError, fmt.Sprintf("не удалось зарезервировать номер набора %v", err.Error()))
+			return
+		}
+
+		progress := job.progress()
+		progress.LinkNotes = notes
+		WriterJSON(w, http.StatusAccepted, progress)
+		return
+	}
+
+	// в потоковом режиме отдаём результаты по мере готовности
+	if format, ok := streamFormat(r); ok {
+		h.streamLinksCheck(w, r, format, links, notes)
+		return
+	}
+
 	// если сервер не останавливают/перезагружают
 	// проверяем доступность каждой ссылки
-	statusLinks, linksSetNum := currentLinksCheck(req.Links)
+	results, linksSetNum, err := h.currentLinksCheck(links, notes)
+	if err != nil {
+		WriterJSON(w, http.StatusInternalServerError, fmt.Sprintf("не удалось сохранить результаты %v", err.Error()))
+		return
+	}
 
 	// формируем и возвращаем ответ
 	resp := ResponseLinks{
-		Links:    statusLinks,
-		LinksNum: linksSetNum,
+		Links:     statusMap(results),
+		States:    stateMap(results),
+		LinksNum:  linksSetNum,
+		LinkNotes: notes,
+	}
+
+	// подробности отдаём только тем клиентам, которые их запросили
+	if req.Detailed {
+		resp.Results = results
 	}
 
 	WriterJSON(w, http.StatusOK, resp)
 }
 
-// currentLinksCheck асинхронно проверяет доступность по текущему набору ссылок
-func currentLinksCheck(links []string) (map[string]string, int) {
+// errNoValidLinks после нормализации не осталось ни одной корректной ссылки (причины - в замечаниях)
+var errNoValidLinks = errors.New("корректных ссылок нет")
 
-	statusLinks := make(map[string]string)
-	results := make(chan Link, len(links))
+// prepareLinks приводит адреса запроса к единому виду (некорректные уходят в замечания),
+// проверяет правила ссылок и возвращает ссылки с наложенными общими правилами запроса
+func (h *Handlers) prepareLinks(req *RequestLinks) ([]data.Link, LinkNotes, error) {
 
-	// запускаем проверки
-	for _, url := range links {
-		go func(u string) {
-			if IsAvailable(u) {
-				results <- Link{u, AvailableStatus}
-			} else {
-				results <- Link{u, NotAvailableStatus}
-			}
-		}(url)
+	// проверяем на всякий случай
+	if len(req.Links) == 0 {
+		return nil, LinkNotes{}, errors.New("ссылок нет")
 	}
 
-	// собираем результаты
-	for i := 0; i < len(links); i++ {
-		res := <-results
-		statusLinks[res.Url] = res.Status
+	// приводим адреса к единому виду, некорректные не проверяем
+	notes := req.normalize()
+	if len(req.Links) == 0 {
+		return nil, notes, errNoValidLinks
 	}
 
-	// сохраняем результаты и получаем номер
-	linksSetNum := data.SaveResults(statusLinks)
+	if _, err := data.ParseProxy(req.Proxy); err != nil {
+		return nil, notes, err
+	}
 
-	return statusLinks, linksSetNum
-}
+	// проверяем условия на содержимое до запуска проверок
+	for _, link := range req.Links {
+		if err := link.Validate(); err != nil {
+			return nil, notes, fmt.Errorf("ссылка %s: %v", data.RedactURL(link.Url), err.Error())
+		}
+	}
 
-// IsAvailable проверяет доступность URL
-func IsAvailable(url string) bool {
+	links := req.targets()
 
-	// добавляем http:// если отсутствует
-	if !strings.Contains(url, "://") {
-		url = "http://" + url
+	// профили TLS и настроенные прокси известны только движку
+	for _, link := range links {
+		if link.TLSProfile != "" && !h.engine.HasTLSProfile(link.TLSProfile) {
+			return nil, notes, fmt.Errorf("неизвестный профиль TLS %q", link.TLSProfile)
+		}
+		if link.Proxy != "" && !h.engine.HasProxy(link.Proxy) {
+			return nil, notes, fmt.Errorf("прокси %s не из настроенных (VERIFI_PROXY, VERIFI_PROXY_RULES)", data.RedactURL(link.Proxy))
+		}
 	}
 
-	// поднимаем клиента со временем ожидания timeoutClient секунд
-	client := http.Client{
-		Timeout: timeoutClient * time.Second,
-	}
+	return links, notes, nil
+}
+
+// currentLinksCheck асинхронно проверяет доступность по текущему набору ссылок;
+// некорректные адреса из замечаний попадают в набор с состоянием invalid
+func (h *Handlers) currentLinksCheck(links []data.Link, notes LinkNotes) (map[string]data.CheckResult, int, error) {
+
+	results := h.engine.Run(links, nil)
+	notes.withOriginsAll(results)
+	maps.Copy(results, notes.invalidResults())
 
-	// отправляем запрос и читаем ответ
-	resp, err := client.Get(url)
+	// сохраняем результаты и получаем номер
+	linksSetNum, err := h.store.SaveResults(results)
 	if err != nil {
-		return false
+		return nil, 0, err
 	}
-	defer resp.Body.Close()
 
-	// считаем статусы 2xx и 3xx доступными
-	return resp.StatusCode >= 200 && resp.StatusCode < 400
+	return results, linksSetNum, nil
 }
 
 // CacheLinksCheck асинхронно проверяет доступность по набору ссылок
-func CacheLinksCheck(links []string) {
-
-	statusLinks := make(map[string]string)
-	results := make(chan Link, len(links))
-
-	// запускаем проверки
-	for _, url := range links {
-		go func(u string) {
-			if IsAvailable(u) {
-				results <- Link{u, AvailableStatus}
-			} else {
-				results <- Link{u, NotAvailableStatus}
-			}
-		}(url)
+func (h *Handlers) CacheLinksCheck(links []data.Link) {
+
+	results := h.engine.Run(links, nil)
+
+	// сохраняем результаты и игнорируем номер
+	if _, err := h.store.SaveResults(results); err != nil {
+		fmt.Printf("не удалось сохранить результаты: %v\n", err)
 	}
+}
 
-	// собираем результаты
-	for i := 0; i < len(links); i++ {
-		res := <-results
-		statusLinks[res.Url] = res.Status
+// statusMap сворачивает подробные результаты в map [{url: status}] для прежних клиентов:
+// ключи - адреса в том виде, в каком они пришли в запросе (origins), а не нормализованные;
+// старые клиенты знают только available и not available, поэтому blocked,
+// как и прочие недоступные состояния, отдаётся как not available
+func statusMap(results map[string]data.CheckResult) map[string]string {
+
+	statusLinks := make(map[string]string, len(results))
+	for url, res := range results {
+		keys := res.Origins
+		if len(keys) == 0 {
+			keys = []string{url}
+		}
+		for _, key := range keys {
+			statusLinks[key] = res.State.Legacy()
+		}
 	}
 
-	// сохраняем результаты и игнорируем номер
-	_ = data.SaveResults(statusLinks)
+	return statusLinks
+}
+
+// stateMap сворачивает подробные результаты в map [{url: state}]
+func stateMap(results map[string]data.CheckResult) map[string]data.Status {
+
+	stateLinks := make(map[string]data.Status, len(results))
+	for url, res := range results {
+		stateLinks[url] = res.State
+	}
+
+	return stateLinks
+}
+
+// IsAvailable проверяет доступность URL
+func IsAvailable(url string) bool {
+
+	return CheckLink(url).State.Available()
+}
+
+// CheckLink проверяет URL и возвращает подробный результат
+func CheckLink(url string) data.CheckResult {
+
+	return checker.Check(url)
 }
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"verifi-server/data"
+)
+
+// состояния асинхронной проверки
+const (
+	JobPending = "pending" // проверка ещё идёт
+	JobDone    = "done"    // проверка завершена, набор сохранён
+	JobFailed  = "failed"  // проверка завершена, но набор сохранить не удалось
+)
+
+// ResponseJob структура ответа о ходе асинхронной проверки
+type ResponseJob struct {
+	LinksNum int                         `json:"links_num"`         // номер набора
+	State    string                      `json:"state"`             // состояние проверки
+	Done     int                         `json:"done"`              // сколько ссылок проверено
+	Total    int                         `json:"total"`             // сколько ссылок всего
+	Links    map[string]string           `json:"links"`             // map [{url: status}] по готовым ссылкам
+	States   map[string]data.Status      `json:"states"`            // map [{url: state}] по готовым ссылкам
+	Results  map[string]data.CheckResult `json:"results,omitempty"` // map [{url: result}] по готовым ссылкам
+	Error    string                      `json:"error,omitempty"`   // причина неудачи
+	LinkNotes
+}
+
+// checkJob асинхронная проверка набора ссылок
+type checkJob struct {
+	id      int
+	total   int
+	done    int
+	state   string
+	err     string
+	notes   LinkNotes // замечания к адресам запроса: по ним результаты получают origins
+	results map[string]data.CheckResult
+	mu      sync.Mutex
+}
+
+// jobRegistry незавершённые асинхронные проверки по номерам наборов
+type jobRegistry struct {
+	jobs map[int]*checkJob
+	mu   sync.RWMutex
+}
+
+// startJob резервирует номер набора и запускает проверку в фоне;
+// некорректные адреса из замечаний готовы сразу, с состоянием invalid
+func (h *Handlers) startJob(links []data.Link, notes LinkNotes) (*checkJob, error) {
+
+	id, err := h.store.ReserveID()
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := notes.invalidResults()
+	job := &checkJob{
+		id:      id,
+		total:   len(links) + len(invalid),
+		done:    len(invalid),
+		state:   JobPending,
+		notes:   notes,
+		results: invalid,
+	}
+
+	h.jobs.mu.Lock()
+	h.jobs.jobs[id] = job
+	h.jobs.mu.Unlock()
+
+	go h.runJob(job, links)
+
+	return job, nil
+}
+
+// runJob проверяет ссылки, копит частичные результаты и по завершении сохраняет набор
+func (h *Handlers) runJob(job *checkJob, links []data.Link) {
+
+	h.engine.Run(links, job.add)
+
+	// в наборе и проверенные ссылки, и некорректные адреса
+	job.mu.Lock()
+	results := maps.Clone(job.results)
+	job.mu.Unlock()
+
+	err := h.store.PutResults(job.id, results)
+
+	job.mu.Lock()
+	if err != nil {
+		job.state = JobFailed
+		job.err = err.Error()
+	} else {
+		job.state = JobDone
+	}
+	job.mu.Unlock()
+
+	// сохранённый набор дальше отдаётся из хранилища, неудачный остаётся в реестре
+	if err == nil {
+		h.jobs.mu.Lock()
+		delete(h.jobs.jobs, job.id)
+		h.jobs.mu.Unlock()
+	}
+}
+
+// add добавляет готовый результат к проверке
+func (j *checkJob) add(res data.CheckResult) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.results[res.Url] = j.notes.withOrigins(res)
+	j.done++
+}
+
+// progress снимок хода проверки
+func (j *checkJob) progress() ResponseJob {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	results := maps.Clone(j.results)
+
+	return ResponseJob{
+		LinksNum: j.id,
+		State:    j.state,
+		Done:     j.done,
+		Total:    j.total,
+		Links:    statusMap(results),
+		States:   stateMap(results),
+		Results:  results,
+		Error:    j.err,
+	}
+}
+
+// CheckGetHandler возвращает ход асинхронной проверки или готовый набор по номеру
+func (h *Handlers) CheckGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.Atoi(r.PathValue("links_num"))
+	if err != nil || id <= 0 {
+		WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("некорректный номер набора %q", r.PathValue("links_num")))
+		return
+	}
+
+	// сначала смотрим незавершённые проверки
+	h.jobs.mu.RLock()
+	job, exists := h.jobs.jobs[id]
+	h.jobs.mu.RUnlock()
+
+	if exists {
+		WriterJSON(w, http.StatusOK, job.progress())
+		return
+	}
+
+	// затем готовые наборы в хранилище
+	results, exists := h.store.GetResults(id)
+	if !exists {
+		WriterJSON(w, http.StatusNotFound, "не найдено записей по такому номеру")
+		return
+	}
+
+	WriterJSON(w, http.StatusOK, ResponseJob{
+		LinksNum: id,
+		State:    JobDone,
+		Done:     len(results),
+		Total:    len(results),
+		Links:    statusMap(results),
+		States:   stateMap(results),
+		Results:  results,
+	})
+}
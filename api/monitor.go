@@ -0,0 +1,361 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"verifi-server/data"
+	"verifi-server/server"
+)
+
+// defaultRunsLimit сколько прогонов отдавать в истории, если клиент не указал limit
+const defaultRunsLimit = 100
+
+// RequestMonitor запрос на заведение монитора: ссылки с правилами, как в /api/check, и расписание
+type RequestMonitor struct {
+	RequestLinks
+	Name     string        `json:"name,omitempty"`     // название для людей
+	Interval data.Duration `json:"interval,omitempty"` // интервал между прогонами, например "5m"
+	Cron     string        `json:"cron,omitempty"`     // либо расписание в формате cron
+}
+
+// ResponseMonitor монитор с его последним состоянием
+type ResponseMonitor struct {
+	data.Monitor
+	State   data.Status      `json:"state"`              // итог последнего прогона (unknown, пока прогонов не было)
+	LastRun *data.MonitorRun `json:"last_run,omitempty"` // последний прогон
+	NextRun *time.Time       `json:"next_run,omitempty"` // когда следующий прогон
+	LinkNotes
+}
+
+// ResponseRuns история прогонов монитора
+type ResponseRuns struct {
+	ID   int               `json:"id"`   // номер монитора
+	Runs []data.MonitorRun `json:"runs"` // прогоны, новые первыми
+}
+
+// monitorScheduler циклы мониторов, запущенные в фоне
+type monitorScheduler struct {
+	stops   map[int]chan struct{} // map [номер монитора] остановка цикла
+	next    map[int]time.Time     // map [номер монитора] время следующего прогона
+	stopped bool
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// StartMonitors подключает хранилище мониторов и ставит сохранённые в нём мониторы в расписание
+func (h *Handlers) StartMonitors(monitors data.MonitorStore) {
+
+	h.monitors = monitors
+	for _, m := range monitors.ListMonitors() {
+		h.schedule(m, false)
+	}
+}
+
+// StopMonitors останавливает расписание и дожидается идущих прогонов
+func (h *Handlers) StopMonitors() {
+
+	h.scheduler.mu.Lock()
+	h.scheduler.stopped = true
+	for id, stop := range h.scheduler.stops {
+		close(stop)
+		delete(h.scheduler.stops, id)
+	}
+	h.scheduler.mu.Unlock()
+
+	h.scheduler.wg.Wait()
+}
+
+// schedule запускает цикл монитора; с now первый прогон идёт сразу
+func (h *Handlers) schedule(m data.Monitor, now bool) {
+
+	h.scheduler.mu.Lock()
+	defer h.scheduler.mu.Unlock()
+
+	if h.scheduler.stopped {
+		return
+	}
+
+	stop := make(chan struct{})
+	h.scheduler.stops[m.ID] = stop
+	h.scheduler.wg.Add(1)
+
+	go func() {
+		defer h.scheduler.wg.Done()
+		h.monitorLoop(m, now, stop)
+	}()
+}
+
+// unschedule останавливает цикл монитора
+func (h *Handlers) unschedule(id int) {
+
+	h.scheduler.mu.Lock()
+	defer h.scheduler.mu.Unlock()
+
+	if stop, exists := h.scheduler.stops[id]; exists {
+		close(stop)
+		delete(h.scheduler.stops, id)
+		delete(h.scheduler.next, id)
+	}
+}
+
+// monitorLoop прогоняет монитор по расписанию, пока его не остановят;
+// затянувшийся прогон не накладывается на следующий: пропущенные срабатывания не догоняются
+func (h *Handlers) monitorLoop(m data.Monitor, now bool, stop <-chan struct{}) {
+
+	defer func() {
+		h.scheduler.mu.Lock()
+		delete(h.scheduler.next, m.ID)
+		h.scheduler.mu.Unlock()
+	}()
+
+	next := time.Now()
+	if !now {
+		next = m.Next(next)
+	}
+
+	for !next.IsZero() {
+		h.scheduler.mu.Lock()
+		h.scheduler.next[m.ID] = next
+		h.scheduler.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// пока сервер останавливают/перезагружают, прогоны пропускаем
+		if !server.IsShutdown() {
+			h.runMonitor(m)
+		}
+
+		next = m.Next(next)
+		if current := time.Now(); next.Before(current) {
+			next = m.Next(current)
+		}
+	}
+}
+
+// runMonitor проверяет ссылки монитора, сохраняет результаты набором и дописывает прогон в историю
+func (h *Handlers) runMonitor(m data.Monitor) {
+
+	run := data.MonitorRun{StartedAt: time.Now()}
+
+	results := h.engine.Run(m.Links, nil)
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	run.States = stateMap(results)
+	run.State = data.Overall(run.States)
+
+	// подробные результаты - обычный набор, по его номеру доступен и pdf отчёт
+	linksNum, err := h.store.SaveResults(results)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.LinksNum = linksNum
+
+	// монитор могли удалить, пока шёл прогон: тогда и набор прогона не нужен
+	if _, exists := h.monitors.GetMonitor(m.ID); !exists {
+		h.deleteRunResults([]data.MonitorRun{run})
+		return
+	}
+	dropped, err := h.monitors.AppendRun(m.ID, run)
+	if err != nil {
+		fmt.Printf("не удалось сохранить прогон монитора %d: %v\n", m.ID, err)
+		h.deleteRunResults([]data.MonitorRun{run})
+		return
+	}
+
+	// наборы прогонов, вытесненных из истории, больше ни откуда не видны
+	h.deleteRunResults(dropped)
+
+	// монитор удалили, пока прогон дописывался в историю
+	if _, exists := h.monitors.GetMonitor(m.ID); !exists {
+		h.deleteRunResults([]data.MonitorRun{run})
+	}
+}
+
+// deleteRunResults удаляет из хранилища наборы с результатами прогонов
+func (h *Handlers) deleteRunResults(runs []data.MonitorRun) {
+
+	for _, run := range runs {
+		if run.LinksNum == 0 {
+			continue
+		}
+		if _, err := h.store.DeleteResults(run.LinksNum); err != nil {
+			fmt.Printf("не удалось удалить набор %d прогона: %v\n", run.LinksNum, err)
+		}
+	}
+}
+
+// monitorView монитор с последним прогоном и временем следующего
+func (h *Handlers) monitorView(m data.Monitor) ResponseMonitor {
+
+	view := ResponseMonitor{Monitor: m.Redacted(), State: data.UnknownStatus}
+
+	if runs, _ := h.monitors.Runs(m.ID, 1); len(runs) != 0 {
+		view.LastRun = &runs[0]
+		view.State = runs[0].State
+	}
+
+	h.scheduler.mu.Lock()
+	if next, exists := h.scheduler.next[m.ID]; exists {
+		view.NextRun = &next
+	}
+	h.scheduler.mu.Unlock()
+
+	return view
+}
+
+// MonitorPostHandler заводит монитор и сразу запускает его первый прогон
+func (h *Handlers) MonitorPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req RequestMonitor
+	var buf bytes.Buffer
+
+	// читаем тело запроса
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("невозможно прочитать тело запроса %v", err.Error()))
+		return
+	}
+
+	// десериализуем запрос клиента
+	err = json.Unmarshal(buf.Bytes(), &req)
+	if err != nil {
+		WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("невозможно десериализовать тело запроса %v", err.Error()))
+		return
+	}
+
+	// ссылки готовим так же, как для разовой проверки
+	links, notes, err := h.prepareLinks(&req.RequestLinks)
+	if errors.Is(err, errNoValidLinks) {
+		WriterJSON(w, http.StatusBadRequest, notes)
+		return
+	}
+	if err != nil {
+		WriterJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m := data.Monitor{
+		Name:      req.Name,
+		Links:     links,
+		Interval:  req.Interval,
+		Cron:      req.Cron,
+		CreatedAt: time.Now(),
+	}
+	if err := m.Validate(); err != nil {
+		WriterJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m, err = h.monitors.SaveMonitor(m)
+	if err != nil {
+		WriterJSON(w, http.StatusInternalServerError, fmt.Sprintf("не удалось сохранить монитор %v", err.Error()))
+		return
+	}
+
+	h.schedule(m, true)
+
+	view := h.monitorView(m)
+	view.LinkNotes = notes
+	WriterJSON(w, http.StatusCreated, view)
+}
+
+// MonitorListHandler возвращает все мониторы с их последним состоянием
+func (h *Handlers) MonitorListHandler(w http.ResponseWriter, r *http.Request) {
+
+	monitors := h.monitors.ListMonitors()
+
+	views := make([]ResponseMonitor, len(monitors))
+	for i, m := range monitors {
+		views[i] = h.monitorView(m)
+	}
+
+	WriterJSON(w, http.StatusOK, views)
+}
+
+// MonitorGetHandler возвращает монитор с его последним состоянием
+func (h *Handlers) MonitorGetHandler(w http.ResponseWriter, r *http.Request) {
+
+	m, ok := h.monitorFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	WriterJSON(w, http.StatusOK, h.monitorView(m))
+}
+
+// MonitorRunsHandler возвращает историю прогонов монитора (?limit=N - последние N)
+func (h *Handlers) MonitorRunsHandler(w http.ResponseWriter, r *http.Request) {
+
+	m, ok := h.monitorFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultRunsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("некорректный limit %q", raw))
+			return
+		}
+		limit = n
+	}
+
+	runs, _ := h.monitors.Runs(m.ID, limit)
+	if runs == nil {
+		runs = []data.MonitorRun{}
+	}
+
+	WriterJSON(w, http.StatusOK, ResponseRuns{ID: m.ID, Runs: runs})
+}
+
+// MonitorDeleteHandler останавливает монитор и удаляет его вместе с историей
+// и наборами с результатами прогонов
+func (h *Handlers) MonitorDeleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	m, ok := h.monitorFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	h.unschedule(m.ID)
+
+	runs, _ := h.monitors.Runs(m.ID, 0)
+	if _, err := h.monitors.DeleteMonitor(m.ID); err != nil {
+		WriterJSON(w, http.StatusInternalServerError, fmt.Sprintf("не удалось удалить монитор %v", err.Error()))
+		return
+	}
+	h.deleteRunResults(runs)
+
+	WriterJSON(w, http.StatusOK, fmt.Sprintf("монитор %d удалён", m.ID))
+}
+
+// monitorFromPath находит монитор по номеру из пути; при неудаче сам отвечает клиенту
+func (h *Handlers) monitorFromPath(w http.ResponseWriter, r *http.Request) (data.Monitor, bool) {
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		WriterJSON(w, http.StatusBadRequest, fmt.Sprintf("некорректный номер монитора %q", r.PathValue("id")))
+		return data.Monitor{}, false
+	}
+
+	m, exists := h.monitors.GetMonitor(id)
+	if !exists {
+		WriterJSON(w, http.StatusNotFound, "не найден монитор с таким номером")
+		return data.Monitor{}, false
+	}
+
+	return m, true
+}
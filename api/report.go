@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"maps"
 	"net/http"
+	neturl "net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"verifi-server/data"
 	"verifi-server/server"
@@ -21,7 +26,7 @@ type RequestCollection struct {
 }
 
 // ReportPostHandler обрабатывает POST запрос для генерации PDF отчета
-func ReportPostHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ReportPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req RequestCollection
 	var buf bytes.Buffer
@@ -60,7 +65,7 @@ func ReportPostHandler(w http.ResponseWriter, r *http.Request) {
 	// с запросами по номерам при перезагрузке сервера.
 
 	// собираем все данные по указанным номерам
-	allResults := collectReportData(req.Links)
+	allResults := h.collectReportData(req.Links)
 	if len(allResults) == 0 {
 		WriterJSON(w, http.StatusNotFound, "не найдено записей по таким номерам")
 		return
@@ -78,12 +83,12 @@ func ReportPostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // collectReportData собирает все результаты по указанным номерам
-func collectReportData(linksList []int) map[string]string {
+func (h *Handlers) collectReportData(linksList []int) map[string]data.CheckResult {
 
-	allResults := make(map[string]string)
+	allResults := make(map[string]data.CheckResult)
 
 	for i := range linksList {
-		if results, exists := data.GetResults(linksList[i]); exists {
+		if results, exists := h.store.GetResults(linksList[i]); exists {
 			maps.Copy(allResults, results)
 		}
 	}
@@ -92,9 +97,9 @@ func collectReportData(linksList []int) map[string]string {
 }
 
 // generatePDF создает PDF файл с отчетом
-func generatePDF(reportData map[string]string) ([]byte, error) {
+func generatePDF(reportData map[string]data.CheckResult) ([]byte, error) {
 
-	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf := gofpdf.New("L", "mm", "A4", "")
 	pdf.AddPage()
 
 	// заголовок
@@ -107,38 +112,104 @@ func generatePDF(reportData map[string]string) ([]byte, error) {
 	pdf.CellFormat(0, 8, fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05")), "", 0, "L", false, 0, "")
 	pdf.Ln(5)
 	pdf.CellFormat(0, 8, fmt.Sprintf("Total URLs: %d", len(reportData)), "", 0, "L", false, 0, "")
+	pdf.Ln(5)
+
+	// медленные ссылки (порог задаётся VERIFI_SLOW_THRESHOLD)
+	slow := 0
+	for _, res := range reportData {
+		if res.Slow {
+			slow++
+		}
+	}
+	pdf.CellFormat(0, 8, fmt.Sprintf("Slow URLs: %d", slow), "", 0, "L", false, 0, "")
 	pdf.Ln(10)
 
 	// заголовки таблицы
-	pdf.SetFont("Arial", "B", 12)
+	pdf.SetFont("Arial", "B", 11)
 	pdf.SetFillColor(240, 240, 240)
-	pdf.CellFormat(120, 10, "URL", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(0, 10, "Status", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(70, 10, "URL", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "Status", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(15, 10, "Code", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(20, 10, "Latency", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(15, 10, "TTFB", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(15, 10, "Checks", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "Cert", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 10, "Redirects", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(0, 10, "Reason", "1", 0, "C", true, 0, "")
 	pdf.Ln(10)
 
 	// данные
-	pdf.SetFont("Arial", "", 11)
-	for url, status := range reportData {
-		// обрезаем слишком длинные URL для лучшего отображения
-		displayURL := url
-		if len(displayURL) > 50 {
-			displayURL = displayURL[:47] + "..."
+	pdf.SetFont("Arial", "", 9)
+	for _, url := range slices.Sorted(maps.Keys(reportData)) {
+		res := reportData[url]
+
+		// URL (обрезаем слишком длинные для лучшего отображения)
+		pdf.CellFormat(70, 8, truncate(latin(url), 40), "1", 0, "L", false, 0, "")
+
+		// состояние с цветом
+		status, r, g, b := statusCell(res)
+		pdf.SetTextColor(r, g, b)
+		pdf.CellFormat(30, 8, status, "1", 0, "C", false, 0, "")
+
+		// возвращаем черный цвет для остальных колонок
+		pdf.SetTextColor(0, 0, 0)
+
+		// код ответа и время проверки
+		code := "-"
+		if res.StatusCode != 0 {
+			code = strconv.Itoa(res.StatusCode)
 		}
+		pdf.CellFormat(15, 8, code, "1", 0, "C", false, 0, "")
 
-		// URL
-		pdf.CellFormat(120, 8, displayURL, "1", 0, "L", false, 0, "")
+		// время проверки и до первого байта, медленные - жирным на розовом фоне
+		latency, ttfb := latencyCells(res)
+		if res.Slow {
+			pdf.SetFont("Arial", "B", 9)
+			pdf.SetFillColor(255, 220, 220)
+			pdf.SetTextColor(200, 0, 0)
+		}
+		pdf.CellFormat(20, 8, latency, "1", 0, "C", res.Slow, 0, "")
+		pdf.CellFormat(15, 8, ttfb, "1", 0, "C", res.Slow, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.SetTextColor(0, 0, 0)
 
-		// status с цветом
-		if status == AvailableStatus {
-			pdf.SetTextColor(0, 128, 0) // зеленый
-			pdf.CellFormat(0, 8, "Available", "1", 0, "C", false, 0, "")
-		} else {
-			pdf.SetTextColor(255, 0, 0) // красный
-			pdf.CellFormat(0, 8, "Not Available", "1", 0, "C", false, 0, "")
+		// сколько проверок содержимого пройдено
+		checks := "-"
+		if len(res.Assertions) != 0 {
+			passed := 0
+			for _, a := range res.Assertions {
+				if a.Passed {
+					passed++
+				}
+			}
+			checks = fmt.Sprintf("%d/%d", passed, len(res.Assertions))
 		}
+		pdf.CellFormat(15, 8, checks, "1", 0, "C", false, 0, "")
+
+		// сертификат: дни до истечения и проблемы, цветом - серьёзность
+		cert, r, g, b := certCell(res)
+		pdf.SetTextColor(r, g, b)
+		pdf.CellFormat(30, 8, cert, "1", 0, "C", false, 0, "")
 
-		// возвращаем черный цвет для следующей строки
+		// перенаправления: число звеньев и конечный хост
+		redirects, r, g, b := redirectCell(res)
+		pdf.SetTextColor(r, g, b)
+		pdf.CellFormat(35, 8, redirects, "1", 0, "C", false, 0, "")
 		pdf.SetTextColor(0, 0, 0)
+
+		// причина недоступности или причины degraded с предупреждением
+		reason := res.ErrorKind
+		if res.Error != "" {
+			reason += ": " + res.Error
+		}
+		if res.State == DegradedStatus {
+			reason = strings.Join(res.Reasons, ", ")
+			if res.Warning != "" {
+				reason += ": " + res.Warning
+			}
+		}
+		pdf.CellFormat(0, 8, truncate(latin(reason), 26), "1", 0, "L", false, 0, "")
+
 		pdf.Ln(8)
 	}
 
@@ -152,6 +223,132 @@ func generatePDF(reportData map[string]string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// statusCell текст и цвет ячейки состояния
+func statusCell(res data.CheckResult) (string, int, int, int) {
+
+	switch res.State {
+	case UpStatus:
+		return "Up", 0, 128, 0 // зеленый
+	case DegradedStatus:
+		if slices.Contains(res.Reasons, data.ReasonFlaky) {
+			return fmt.Sprintf("Degraded (%d tries)", res.Attempts), 230, 140, 0 // оранжевый
+		}
+		return "Degraded", 230, 140, 0
+	case BlockedStatus:
+		return "Blocked", 128, 128, 128 // серый
+	case InvalidStatus:
+		return "Invalid", 128, 0, 128 // фиолетовый
+	case UnknownStatus:
+		return "Unknown", 0, 90, 200 // синий
+	default:
+		return "Down", 255, 0, 0 // красный
+	}
+}
+
+// latencyCells тексты ячеек полного времени проверки и времени до первого байта
+func latencyCells(res data.CheckResult) (string, string) {
+
+	if res.Timings == nil {
+		return fmt.Sprintf("%d ms", res.LatencyMs), "-"
+	}
+
+	ttfb := "-"
+	if res.Timings.TTFBMs > 0 {
+		ttfb = fmt.Sprintf("%.0f ms", res.Timings.TTFBMs)
+	}
+
+	return fmt.Sprintf("%.0f ms", res.Timings.TotalMs), ttfb
+}
+
+// certCell текст и цвет ячейки сертификата
+func certCell(res data.CheckResult) (string, int, int, int) {
+
+	if res.TLS == nil {
+		return "-", 0, 0, 0
+	}
+
+	text := fmt.Sprintf("%d days", res.TLS.DaysLeft)
+
+	switch {
+	case res.TLS.HostnameMismatch:
+		return text + ", mismatch", 255, 0, 0
+	case res.TLS.SelfSigned:
+		return text + ", self-signed", 255, 0, 0
+	case res.TLS.DaysLeft < 0:
+		return "expired", 255, 0, 0
+	case res.Warning != "":
+		return text, 230, 140, 0
+	default:
+		return text, 0, 128, 0
+	}
+}
+
+// redirectCell текст и цвет ячейки перенаправлений
+func redirectCell(res data.CheckResult) (string, int, int, int) {
+
+	if len(res.Redirects) == 0 {
+		return "-", 0, 0, 0
+	}
+
+	text := fmt.Sprintf("%d", len(res.Redirects))
+	if u, err := neturl.Parse(res.FinalURL); err == nil && u.Host != "" {
+		text += " -> " + u.Host
+	}
+	text = truncate(text, 20)
+
+	switch {
+	case res.HTTPSDowngrade:
+		return text, 255, 0, 0
+	case res.CrossDomainRedirect:
+		return text, 230, 140, 0
+	default:
+		return text, 0, 0, 0
+	}
+}
+
+// truncate обрезает строку до max символов для вывода в ячейку
+func truncate(s string, max int) string {
+
+	if len(s) > max {
+		return s[:max-3] + "..."
+	}
+
+	return s
+}
+
+// cyrillic транслит русских букв для PDF
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// latin приводит текст к ASCII: стандартные шрифты PDF не знают кириллицы,
+// поэтому русские причины пишутся транслитом, прочие символы - знаком ?
+func latin(s string) string {
+
+	var b strings.Builder
+
+	for _, r := range s {
+		lower := unicode.ToLower(r)
+		tr, ok := cyrillic[lower]
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case ok && lower != r && tr != "":
+			b.WriteString(strings.ToUpper(tr[:1]) + tr[1:])
+		case ok:
+			b.WriteString(tr)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
 // sendPDFResponse отправляет PDF файл в ответе
 func sendPDFResponse(w http.ResponseWriter, pdfData []byte) {
 
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"verifi-server/data"
+)
+
+// форматы потоковой выдачи
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeSSE    = "text/event-stream"
+)
+
+// StreamSummary завершающая запись потока с номером набора
+type StreamSummary struct {
+	LinksNum int    `json:"links_num"`       // номер набора
+	Total    int    `json:"total"`           // сколько ссылок в наборе (вместе с некорректными)
+	Error    string `json:"error,omitempty"` // причина, по которой набор не сохранён
+	LinkNotes
+}
+
+// streamFormat определяет, просит ли клиент потоковую выдачу, и в каком формате
+func streamFormat(r *http.Request) (string, bool) {
+
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, contentTypeSSE):
+		return contentTypeSSE, true
+
+	case strings.Contains(accept, contentTypeNDJSON), r.URL.Query().Get("stream") == "true":
+		return contentTypeNDJSON, true
+
+	default:
+		return "", false
+	}
+}
+
+// streamLinksCheck отдаёт результат по каждой ссылке по мере готовности (некорректные адреса -
+// сразу, с состоянием invalid), а после сохранения набора - завершающую запись с его номером
+// и замечаниями к адресам; если клиент отключился, оставшиеся ссылки не проверяются и набор не сохраняется
+func (h *Handlers) streamLinksCheck(w http.ResponseWriter, r *http.Request, format string, links []data.Link, notes LinkNotes) {
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	invalid := notes.invalidResults()
+	for _, res := range invalid {
+		writeStreamEvent(w, format, "result", res)
+	}
+	rc.Flush()
+
+	results := h.engine.RunContext(r.Context(), links, func(res data.CheckResult) {
+		writeStreamEvent(w, format, "result", notes.withOrigins(res))
+		rc.Flush()
+	})
+
+	// ответ читать уже некому
+	if r.Context().Err() != nil {
+		return
+	}
+
+	notes.withOriginsAll(results)
+	maps.Copy(results, invalid)
+	summary := StreamSummary{Total: len(links) + len(invalid), LinkNotes: notes}
+
+	id, err := h.store.SaveResults(results)
+	if err != nil {
+		summary.Error = fmt.Sprintf("не удалось сохранить результаты %v", err.Error())
+	} else {
+		summary.LinksNum = id
+	}
+
+	writeStreamEvent(w, format, "done", summary)
+	rc.Flush()
+}
+
+// writeStreamEvent пишет одну запись потока: строку NDJSON или событие SSE
+func writeStreamEvent(w http.ResponseWriter, format, event string, v any) {
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	if format == contentTypeSSE {
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, js)
+		return
+	}
+
+	w.Write(append(js, '\n'))
+}
@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"verifi-server/data"
+)
+
+// evaluate проверяет тело ответа по условиям ссылки; truncated - тело прочитано
+// не целиком (длиннее MaxBodyBytes), тогда невыполненные условия называют это причиной
+func evaluate(assertions []data.Assertion, body []byte, truncated bool) []data.AssertionResult {
+
+	results := make([]data.AssertionResult, len(assertions))
+
+	for i, a := range assertions {
+		results[i] = data.AssertionResult{Assertion: a, Passed: true}
+
+		if reason := check(a, body, truncated); reason != "" {
+			results[i].Passed = false
+			results[i].Reason = reason
+		}
+	}
+
+	return results
+}
+
+// check проверяет одно условие и возвращает причину невыполнения или пустую строку
+func check(a data.Assertion, body []byte, truncated bool) string {
+
+	// тело не читалось вовсе (VERIFI_CHECK_MAX_BODY=0) - проверять нечего
+	if truncated && len(body) == 0 {
+		return "тело ответа не прочитано: VERIFI_CHECK_MAX_BODY=0"
+	}
+
+	// чего нет в прочитанной части, может найтись дальше - поэтому
+	// на обрезанном теле не выполняются и Contains, и NotContains
+	cut := ""
+	if truncated {
+		cut = fmt.Sprintf(" в первых %d байтах (тело обрезано по VERIFI_CHECK_MAX_BODY)", len(body))
+	}
+
+	switch {
+	case a.Contains != "":
+		if !bytes.Contains(body, []byte(a.Contains)) {
+			return fmt.Sprintf("в теле нет %q%s", a.Contains, cut)
+		}
+
+	case a.NotContains != "":
+		if bytes.Contains(body, []byte(a.NotContains)) {
+			return fmt.Sprintf("в теле есть %q", a.NotContains)
+		}
+		if truncated {
+			return fmt.Sprintf("отсутствие %q проверено только%s", a.NotContains, cut)
+		}
+
+	case a.Regex != "":
+		re, err := regexp.Compile(a.Regex)
+		if err != nil {
+			return fmt.Sprintf("некорректное регулярное выражение %q", a.Regex)
+		}
+		if !re.Match(body) {
+			return fmt.Sprintf("тело не совпадает с %q%s", a.Regex, cut)
+		}
+
+	case a.JSONPath != "":
+		// обрезанный JSON не разобрать
+		if truncated {
+			return fmt.Sprintf("тело ответа обрезано на %d байтах (VERIFI_CHECK_MAX_BODY), JSON не разобрать", len(body))
+		}
+
+		var doc, want any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Sprintf("тело не JSON: %v", err)
+		}
+		if err := json.Unmarshal(a.Equals, &want); err != nil {
+			return fmt.Sprintf("некорректное значение equals: %v", err)
+		}
+
+		got, err := lookup(doc, a.JSONPath)
+		if err != nil {
+			return err.Error()
+		}
+
+		gotJS, _ := json.Marshal(got)
+		wantJS, _ := json.Marshal(want)
+		if !bytes.Equal(gotJS, wantJS) {
+			return fmt.Sprintf("%s = %s, ожидалось %s", a.JSONPath, gotJS, wantJS)
+		}
+	}
+
+	return ""
+}
+
+// lookup находит значение по пути вида $.data.items[0].status или data.items.0.status
+func lookup(doc any, path string) (any, error) {
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("поле %q не найдено", key)
+			}
+			cur = v
+
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("индекса %q нет в массиве", key)
+			}
+			cur = node[idx]
+
+		default:
+			return nil, fmt.Errorf("поле %q не найдено", key)
+		}
+	}
+
+	return cur, nil
+}
@@ -0,0 +1,321 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"verifi-server/data"
+)
+
+var (
+	// defaultEngine движок для разовых проверок вне API
+	defaultEngine *Engine
+	defaultOnce   sync.Once
+)
+
+// Check проверяет URL движком с настройками по умолчанию
+func Check(url string) data.CheckResult {
+
+	defaultOnce.Do(func() {
+		if defaultEngine == nil {
+			defaultEngine = NewEngine(DefaultConfig())
+		}
+	})
+
+	return defaultEngine.Check(url)
+}
+
+// SetDefaultEngine назначает движок для разовых проверок вне API (вызывать до первой проверки)
+func SetDefaultEngine(e *Engine) {
+
+	defaultEngine = e
+}
+
+// Checker проверяет ссылки одной или нескольких схем (http, tcp, dns...).
+// Контекст ограничивает время попытки; повторы выполняет движок
+type Checker interface {
+	Check(ctx context.Context, link data.Link) data.CheckResult
+}
+
+// CheckerFunc функция, удовлетворяющая интерфейсу Checker
+type CheckerFunc func(ctx context.Context, link data.Link) data.CheckResult
+
+// Check вызывает саму функцию
+func (f CheckerFunc) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	return f(ctx, link)
+}
+
+// Register назначает проверку для схемы, заменяя прежнюю
+func (e *Engine) Register(scheme string, c Checker) {
+
+	e.checkersMu.Lock()
+	defer e.checkersMu.Unlock()
+
+	e.checkers[strings.ToLower(scheme)] = c
+}
+
+// checkerFor ищет проверку для схемы
+func (e *Engine) checkerFor(scheme string) (Checker, bool) {
+
+	e.checkersMu.RLock()
+	defer e.checkersMu.RUnlock()
+
+	c, ok := e.checkers[scheme]
+
+	return c, ok
+}
+
+// attempt одна попытка проверки ссылки проверкой, назначенной для её схемы
+func (e *Engine) attempt(link data.Link) data.CheckResult {
+
+	scheme := schemeOf(link.Url)
+
+	c, ok := e.checkerFor(scheme)
+	if !ok {
+		res := newResult(link)
+		res.Protocol = scheme
+		res.ErrorKind = data.ErrorKindUnsupported
+		res.Error = fmt.Sprintf("нет проверки для схемы %q", scheme)
+		return res
+	}
+
+	if link.TLSProfile != "" && !e.HasTLSProfile(link.TLSProfile) {
+		res := newResult(link)
+		res.Protocol = scheme
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = fmt.Sprintf("неизвестный профиль TLS %q", link.TLSProfile)
+		return res
+	}
+
+	// прокси попытки: только из настроенных администратором
+	proxy, err := e.proxyFor(link)
+	if err != nil {
+		res := newResult(link)
+		res.Protocol = scheme
+		res.ErrorKind = data.ErrorKindInvalid
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			res.ErrorKind = data.ErrorKindBlocked
+		}
+		res.Error = err.Error()
+		return res
+	}
+
+	// время на всю попытку
+	timeout := e.cfg.Timeout
+	if link.Policy != nil && link.Policy.Timeout > 0 {
+		timeout = time.Duration(link.Policy.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(withProxy(context.Background(), proxy), timeout)
+	defer cancel()
+
+	// через прокси имя разрешает он сам, поэтому правила
+	// исходящих соединений сверяем с тем, что записано в ссылке
+	if proxy != nil {
+		if ok, reason := e.egress.allowedName(ctx, hostKey(link.Url)); !ok {
+			res := newResult(link)
+			res.Protocol = scheme
+			res.Proxy = proxy.Redacted()
+			res.ErrorKind = data.ErrorKindBlocked
+			res.Error = (&BlockedError{Host: hostKey(link.Url), Reason: reason}).Error()
+			return res
+		}
+	}
+
+	tracer, ctx := newPhaseTracer(ctx)
+
+	res := c.Check(ctx, link)
+	res.Protocol = scheme
+	res.Timings = tracer.timings()
+	res.Slow = e.cfg.SlowThreshold > 0 && res.Timings.TotalMs >= ms(e.cfg.SlowThreshold)
+	res.TLSProfile = link.TLSProfile
+	if proxy != nil {
+		res.Proxy = proxy.Redacted()
+	}
+
+	// проверки, написанные до появления состояний, заполняют только статус
+	if res.State == "" {
+		res.State = data.ParseStatus(res.Status)
+	}
+
+	return res
+}
+
+// newResult заготовка результата: недоступен, пока проверка не доказала обратное
+func newResult(link data.Link) data.CheckResult {
+
+	return data.CheckResult{
+		Url:       link.Url,
+		State:     data.DownStatus,
+		CheckedAt: time.Now(),
+	}
+}
+
+// schemeOf схема ссылки в нижнем регистре (http, если не указана)
+func schemeOf(link string) string {
+
+	scheme, _, ok := strings.Cut(link, "://")
+	if !ok {
+		return "http"
+	}
+
+	return strings.ToLower(scheme)
+}
+
+// Check проверяет URL с правилами по умолчанию
+func (e *Engine) Check(url string) data.CheckResult {
+
+	return e.CheckLink(data.Link{Url: url})
+}
+
+// CheckLink проверяет ссылку по её правилам, при неудаче повторяя попытки
+// с нарастающей паузой, и возвращает результат последней попытки;
+// с CompareDirect ссылка, идущая через прокси, проверяется ещё и напрямую.
+// Секреты ссылки в результате заменяются на data.Redacted
+func (e *Engine) CheckLink(link data.Link) data.CheckResult {
+
+	res := e.checkLink(link)
+
+	if link.CompareDirect && res.Proxy != "" {
+		direct := link
+		direct.Proxy = data.ProxyDirect
+		direct.CompareDirect = false
+		d := e.checkLink(direct)
+		res.Direct = &d
+	}
+
+	// пароли и токены ссылки не должны попасть ни в ответ, ни в хранилище, ни в отчёт
+	res.Redact(link.Secrets())
+
+	return res
+}
+
+// checkLink проверка ссылки с повторами
+func (e *Engine) checkLink(link data.Link) data.CheckResult {
+
+	var res data.CheckResult
+
+	for attempt := 1; ; attempt++ {
+		res = e.attempt(link)
+		res.Attempts = attempt
+		if link.Policy != nil && !link.Policy.IsZero() {
+			res.Policy = link.Policy
+		}
+
+		if res.State.Available() {
+			res.Flaky = attempt > 1
+			res.Settle()
+			return res
+		}
+
+		if attempt > e.cfg.Retries || !retryable(res) || !repeatable(link) {
+			res.Settle()
+			return res
+		}
+
+		time.Sleep(e.backoff(attempt))
+	}
+}
+
+// retryable решает, имеет ли смысл повторять проверку:
+// сетевые сбои, 5xx и 429 повторяем; остальные ответы ресурса, содержимое,
+// сертификаты и запреты от повтора не изменятся
+func retryable(res data.CheckResult) bool {
+
+	switch res.ErrorKind {
+	case data.ErrorKindStatus:
+		return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+
+	case data.ErrorKindContent, data.ErrorKindTLS, data.ErrorKindUnsupported, data.ErrorKindInvalid, data.ErrorKindBlocked:
+		return false
+
+	default:
+		return true
+	}
+}
+
+// repeatable решает, можно ли повторить сам запрос ссылки: GET, HEAD и OPTIONS
+// ничего не меняют, а POST, PUT, DELETE и прочие повторяются, только если ссылка
+// это разрешила (retry_unsafe) - иначе, например, заказ мог бы оформиться дважды
+func repeatable(link data.Link) bool {
+
+	switch strings.ToUpper(link.Method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return link.RetryUnsafe
+	}
+}
+
+// backoff пауза перед повтором: BackoffBase * 2^(attempt-1), не больше BackoffMax,
+// со случайным разбросом в пределах половины, чтобы повторы к одному хосту не шли залпом
+func (e *Engine) backoff(attempt int) time.Duration {
+
+	if e.cfg.BackoffBase <= 0 {
+		return 0
+	}
+
+	d := e.cfg.BackoffBase << (attempt - 1)
+	if d <= 0 || (e.cfg.BackoffMax > 0 && d > e.cfg.BackoffMax) {
+		d = e.cfg.BackoffMax
+	}
+
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+
+	return half + rand.N(half+1)
+}
+
+// classifyError определяет вид сетевой ошибки
+func classifyError(err error) string {
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var blockedErr *BlockedError
+	var proxyErr *ProxyError
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &blockedErr):
+		return data.ErrorKindBlocked
+
+	// туннель к прокси открывает и http транспорт: его ошибки приходят как proxyconnect
+	case errors.As(err, &proxyErr), errors.As(err, &opErr) && opErr.Op == "proxyconnect":
+		return data.ErrorKindProxy
+
+	case errors.As(err, &dnsErr):
+		return data.ErrorKindDNS
+
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return data.ErrorKindRefused
+
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		errors.As(err, &opErr) && opErr.Op == "remote error": // сервер отверг рукопожатие (нет сертификата клиента, версия TLS)
+		return data.ErrorKindTLS
+
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return data.ErrorKindTimeout
+
+	default:
+		return data.ErrorKindOther
+	}
+}
@@ -0,0 +1,172 @@
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config настройки движка проверок
+type Config struct {
+	Workers int           // сколько проверок может идти одновременно на весь сервер
+	PerHost int           // сколько проверок может идти одновременно к одному хосту (0 - без ограничения)
+	Timeout time.Duration // время на запрос клиенту
+
+	Retries     int           // сколько раз повторить неудачную проверку
+	BackoffBase time.Duration // пауза перед первым повтором, далее удваивается
+	BackoffMax  time.Duration // верхняя граница паузы между повторами
+
+	Method       string // способ опроса: MethodHead или MethodGet
+	RangeGET     bool   // запрашивать в GET только первые MaxBodyBytes байт (Range)
+	MaxBodyBytes int64  // сколько байт тела дочитывать, прежде чем бросить соединение
+
+	CertWarnDays  int           // за сколько дней до истечения сертификата считать ресурс degraded (0 - не считать)
+	SlowThreshold time.Duration // с какого времени проверки ссылка считается медленной (0 - не отмечать)
+
+	DNSResolver string // адрес резолвера для dns ссылок без своего (host:port, пусто - системный)
+
+	EgressAllowPrivate bool     // разрешить обращения к внутренним адресам (частные сети, loopback, link-local)
+	EgressAllow        []string // CIDR и домены, к которым можно обращаться даже если они внутренние
+	EgressDeny         []string // CIDR и домены, к которым обращаться нельзя
+
+	Proxy      string   // общий прокси для всех проверок (http://, https://, socks5://, с user:pass@ при необходимости)
+	ProxyRules []string // правила вида домен=прокси (или домен=direct), первое подходящее важнее общего прокси
+
+	TLSProfiles map[string]TLSProfile // именованные профили TLS, выбираемые ссылкой или запросом
+}
+
+// DefaultConfig настройки по умолчанию
+func DefaultConfig() Config {
+
+	return Config{
+		Workers: 64,
+		PerHost: 4,
+		Timeout: 3 * time.Second,
+
+		Retries:     2,
+		BackoffBase: 200 * time.Millisecond,
+		BackoffMax:  2 * time.Second,
+
+		Method:       MethodHead,
+		MaxBodyBytes: 64 << 10,
+
+		CertWarnDays:  14,
+		SlowThreshold: time.Second,
+	}
+}
+
+// ConfigFromEnv настройки по умолчанию, переопределённые переменными окружения
+func ConfigFromEnv() Config {
+
+	cfg := DefaultConfig()
+
+	cfg.Workers = envInt("VERIFI_CHECK_WORKERS", cfg.Workers)
+	cfg.PerHost = envInt("VERIFI_CHECK_PER_HOST", cfg.PerHost)
+	cfg.Timeout = envDuration("VERIFI_CHECK_TIMEOUT", cfg.Timeout)
+	cfg.Retries = envInt("VERIFI_CHECK_RETRIES", cfg.Retries)
+	cfg.BackoffBase = envDuration("VERIFI_CHECK_BACKOFF", cfg.BackoffBase)
+	cfg.BackoffMax = envDuration("VERIFI_CHECK_BACKOFF_MAX", cfg.BackoffMax)
+	cfg.RangeGET = envBool("VERIFI_CHECK_RANGE", cfg.RangeGET)
+	cfg.MaxBodyBytes = int64(envInt("VERIFI_CHECK_MAX_BODY", int(cfg.MaxBodyBytes)))
+	cfg.CertWarnDays = envInt("VERIFI_CERT_WARN_DAYS", cfg.CertWarnDays)
+	cfg.SlowThreshold = envDuration("VERIFI_SLOW_THRESHOLD", cfg.SlowThreshold)
+	cfg.DNSResolver = os.Getenv("VERIFI_DNS_RESOLVER")
+	cfg.EgressAllowPrivate = envBool("VERIFI_EGRESS_ALLOW_PRIVATE", cfg.EgressAllowPrivate)
+	cfg.EgressAllow = envList("VERIFI_EGRESS_ALLOW")
+	cfg.EgressDeny = envList("VERIFI_EGRESS_DENY")
+	cfg.Proxy = os.Getenv("VERIFI_PROXY")
+	cfg.ProxyRules = envList("VERIFI_PROXY_RULES")
+	cfg.TLSProfiles = envTLSProfiles("VERIFI_TLS_PROFILES")
+
+	if method, ok := os.LookupEnv("VERIFI_CHECK_METHOD"); ok && (method == MethodHead || method == MethodGet) {
+		cfg.Method = method
+	}
+
+	return cfg
+}
+
+// envInt читает целое из переменной окружения, при отсутствии или ошибке возвращает def
+func envInt(name string, def int) int {
+
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return def
+	}
+
+	return n
+}
+
+// envTLSProfiles читает профили TLS из JSON файла, путь к которому задан в переменной окружения:
+// {"internal": {"ca_file": "ca.pem", "cert_file": "client.pem", "key_file": "client.key", "min_version": "1.2"}}
+func envTLSProfiles(name string) map[string]TLSProfile {
+
+	path := os.Getenv(name)
+	if path == "" {
+		return nil
+	}
+
+	var profiles map[string]TLSProfile
+	b, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(b, &profiles)
+	}
+	if err != nil {
+		fmt.Printf("не удалось прочитать профили TLS из %s: %v\n", path, err)
+		return nil
+	}
+
+	return profiles
+}
+
+// envList читает список через запятую из переменной окружения
+func envList(name string) []string {
+
+	var list []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// envBool читает флаг (true/false, 1/0) из переменной окружения
+func envBool(name string, def bool) bool {
+
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+// envDuration читает длительность (например, 3s) из переменной окружения
+func envDuration(name string, def time.Duration) time.Duration {
+
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return def
+	}
+
+	return d
+}
@@ -0,0 +1,190 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"verifi-server/data"
+)
+
+// dnsChecker разрешает имя по ссылкам вида dns://[resolver/]name?type=A
+// (как в RFC 4501: необязательный authority - адрес резолвера)
+type dnsChecker struct {
+	e *Engine
+}
+
+// Check одна попытка разрешения имени
+func (c dnsChecker) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	res := newResult(link)
+
+	name, resolver, recordType, err := parseDNSLink(link.Url)
+	if err != nil {
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = err.Error()
+		return res
+	}
+	// резолвер из VERIFI_DNS_RESOLVER задал администратор, как и прокси, - ему доверяем;
+	// резолвер из ссылки сверяется с правилами исходящих соединений
+	trusted := resolver == ""
+	if trusted {
+		resolver = c.e.cfg.DNSResolver
+	}
+
+	res.DNS = &data.DNSInfo{
+		Type:     recordType,
+		Resolver: resolver,
+	}
+
+	// отказ при подключении к резолверу net.Resolver прячет в *net.DNSError,
+	// поэтому запрет выясняем заранее
+	if !trusted {
+		host, _, _ := net.SplitHostPort(resolver)
+		if ok, reason := c.e.egress.allowedName(ctx, host); !ok {
+			res.ErrorKind = data.ErrorKindBlocked
+			res.Error = (&BlockedError{Host: host, Reason: reason}).Error()
+			return res
+		}
+	}
+
+	start := time.Now()
+	records, err := lookupRecords(ctx, c.e.newResolver(resolver, trusted), name, recordType)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		return res
+	}
+	res.DNS.Records = records
+
+	// каждое ожидаемое значение должно быть среди ответов
+	for _, want := range link.ExpectRecords {
+		if !slices.Contains(records, normalizeRecord(want)) {
+			res.ErrorKind = data.ErrorKindContent
+			res.Error = fmt.Sprintf("запись %s %q не найдена", recordType, want)
+			return res
+		}
+	}
+
+	res.State = data.UpStatus
+
+	return res
+}
+
+// parseDNSLink разбирает dns ссылку на имя, резолвер и тип записи
+func parseDNSLink(link string) (string, string, string, error) {
+
+	u, err := neturl.Parse(link)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// dns://name - имя в authority; dns://resolver/name и dns:///name - имя в пути
+	name, resolver := u.Host, ""
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		name, resolver = path, u.Host
+	}
+	if name == "" {
+		return "", "", "", fmt.Errorf("dns ссылка записывается как dns://[резолвер/]имя?type=A")
+	}
+
+	// резолвер без порта - стандартный 53
+	if resolver != "" && u.Port() == "" {
+		resolver = net.JoinHostPort(resolver, "53")
+	}
+
+	recordType := strings.ToUpper(u.Query().Get("type"))
+	if recordType == "" {
+		recordType = "A"
+	}
+	if !slices.Contains([]string{"A", "AAAA", "CNAME", "MX", "TXT"}, recordType) {
+		return "", "", "", fmt.Errorf("неподдерживаемый тип записи %q", recordType)
+	}
+
+	return name, resolver, recordType, nil
+}
+
+// newResolver резолвер, обращающийся к addr (пустой addr - системные настройки);
+// к резолверу из ссылки подключаемся по правилам исходящих соединений,
+// к настроенному (trusted) - напрямую: он обычно во внутренней сети
+func (e *Engine) newResolver(addr string, trusted bool) *net.Resolver {
+
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			if trusted {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			}
+			return e.dialContext(ctx, network, addr)
+		},
+	}
+}
+
+// lookupRecords запрашивает записи нужного типа и приводит их к строкам
+func lookupRecords(ctx context.Context, r *net.Resolver, name, recordType string) ([]string, error) {
+
+	// полное имя, чтобы не подставлялись домены поиска
+	fqdn := strings.TrimSuffix(name, ".") + "."
+
+	var records []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, cname)
+
+	case "MX":
+		mxs, err := r.LookupMX(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			records = append(records, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, txts...)
+	}
+
+	for i := range records {
+		records[i] = normalizeRecord(records[i])
+	}
+
+	return records, nil
+}
+
+// normalizeRecord приводит запись к виду для сравнения: без точки в конце имён и в нижнем регистре
+func normalizeRecord(record string) string {
+
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(record), "."))
+}
@@ -0,0 +1,216 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// cgnat общее адресное пространство провайдеров (RFC 6598), наружу из него не ходят
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// BlockedError обращение к адресу запрещено правилами исходящих соединений
+type BlockedError struct {
+	Host   string // хост из ссылки
+	Reason string // какое правило сработало
+}
+
+// Error текст ошибки
+func (e *BlockedError) Error() string {
+
+	return fmt.Sprintf("соединение с %s запрещено: %s", e.Host, e.Reason)
+}
+
+// egressPolicy правила исходящих соединений: запрет сильнее разрешения,
+// разрешение сильнее запрета внутренних адресов по умолчанию
+type egressPolicy struct {
+	allowPrivate bool
+	allowNets    []netip.Prefix
+	allowDomains []string
+	denyNets     []netip.Prefix
+	denyDomains  []string
+}
+
+// newEgressPolicy разбирает списки правил: CIDR или IP адреса и домены
+// (домен действует и на все свои поддомены)
+func newEgressPolicy(cfg Config) egressPolicy {
+
+	p := egressPolicy{allowPrivate: cfg.EgressAllowPrivate}
+	p.allowNets, p.allowDomains = parseRules(cfg.EgressAllow)
+	p.denyNets, p.denyDomains = parseRules(cfg.EgressDeny)
+
+	return p
+}
+
+// parseRules делит правила на сети и домены
+func parseRules(rules []string) ([]netip.Prefix, []string) {
+
+	var nets []netip.Prefix
+	var domains []string
+
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(rule); err == nil {
+			nets = append(nets, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(rule); err == nil {
+			nets = append(nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		if strings.Contains(rule, "/") {
+			fmt.Printf("некорректное правило исходящих соединений %q пропущено\n", rule)
+			continue
+		}
+
+		domains = append(domains, strings.TrimSuffix(strings.TrimLeft(rule, "*."), "."))
+	}
+
+	return nets, domains
+}
+
+// allowed решает, можно ли соединяться с addr, полученным для host;
+// при запрете возвращает причину
+func (p egressPolicy) allowed(host string, addr netip.Addr) (bool, string) {
+
+	addr = addr.Unmap()
+
+	if domainMatch(host, p.denyDomains) {
+		return false, "домен запрещён"
+	}
+	if prefixMatch(addr, p.denyNets) {
+		return false, "адрес " + addr.String() + " запрещён"
+	}
+
+	if domainMatch(host, p.allowDomains) || prefixMatch(addr, p.allowNets) {
+		return true, ""
+	}
+
+	if !p.allowPrivate && internal(addr) {
+		return false, addr.String() + " - внутренний адрес"
+	}
+
+	return true, ""
+}
+
+// internal адреса внутренних сетей, самого сервера и облачных метаданных
+func internal(addr netip.Addr) bool {
+
+	return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified() || cgnat.Contains(addr)
+}
+
+// domainMatch совпадает ли host с одним из доменов или их поддоменами
+func domainMatch(host string, domains []string) bool {
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prefixMatch входит ли адрес в одну из сетей
+func prefixMatch(addr netip.Addr, nets []netip.Prefix) bool {
+
+	for _, n := range nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// internalSuffixes имена, которые не бывают публичными: сам сервер, локальные сети
+// и служебные домены облаков (metadata.google.internal)
+var internalSuffixes = []string{"localhost", "local", "localdomain", "internal", "intranet", "lan", "home.arpa"}
+
+// allowedName решает, можно ли обращаться к host через прокси. Имя разрешает прокси,
+// поэтому сервер разрешает его сам лишь для сверки: если имя разрешилось, каждый адрес
+// проверяется как при прямом соединении; если нет - запрещены внутренние имена
+// (localhost, *.internal, *.local, имена без точки), чтобы прокси не открыл к ним дорогу
+func (p egressPolicy) allowedName(ctx context.Context, host string) (bool, string) {
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return p.allowed(host, addr)
+	}
+
+	if domainMatch(host, p.denyDomains) {
+		return false, "домен запрещён"
+	}
+	if domainMatch(host, p.allowDomains) {
+		return true, ""
+	}
+
+	if ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host); err == nil && len(ips) != 0 {
+		for _, ip := range ips {
+			if ok, reason := p.allowed(host, ip); !ok {
+				return false, reason
+			}
+		}
+		return true, ""
+	}
+
+	if !p.allowPrivate && (!strings.Contains(host, ".") || domainMatch(host, internalSuffixes)) {
+		return false, host + " - внутреннее имя"
+	}
+
+	return true, ""
+}
+
+// dialDirect соединение без прокси:
+// имя разрешается один раз, каждый адрес сверяется с правилами, и соединение
+// идёт на уже проверенный адрес - подмена ответа DNS между проверкой и
+// подключением (DNS rebinding) не поможет обойти запрет
+func (e *Engine) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var blocked *BlockedError
+	var lastErr error
+
+	for _, ip := range ips {
+		if ok, reason := e.egress.allowed(host, ip); !ok {
+			blocked = &BlockedError{Host: host, Reason: reason}
+			continue
+		}
+
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if blocked != nil {
+		return nil, blocked
+	}
+
+	return nil, errors.New("нет адресов для " + host)
+}
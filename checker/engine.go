@@ -0,0 +1,310 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+
+	"verifi-server/data"
+)
+
+// Engine общий движок проверок: ограничивает число одновременных проверок
+// на весь сервер и на каждый хост, а задания из разных наборов берёт по очереди,
+// чтобы один большой набор не задерживал остальные
+type Engine struct {
+	cfg       Config
+	transport *http.Transport // общий транспорт всех проверок
+
+	tlsConfigs map[string]*tls.Config     // map [профиль] настройки TLS
+	transports map[string]*http.Transport // map [профиль] транспорт с настройками профиля
+	egress     egressPolicy               // куда можно обращаться
+
+	proxy      *neturl.URL // общий прокси (nil - напрямую)
+	proxyRules []proxyRule // прокси для отдельных доменов
+
+	checkers   map[string]Checker // map [scheme] проверка
+	checkersMu sync.RWMutex
+
+	batches  []*batch       // наборы, в которых остались непроверенные ссылки
+	next     int            // с какого набора начинать поиск следующего задания
+	inFlight map[string]int // сколько проверок идёт к каждому хосту
+	closed   bool
+	done     chan struct{} // закрывается в Close, будит ожидающих результатов
+	mu       sync.Mutex
+	cond     *sync.Cond
+}
+
+// batch ссылки одного вызова Run, ожидающие проверки
+type batch struct {
+	hosts   []string               // хосты с ожидающими ссылками в порядке обхода
+	queue   map[string][]data.Link // map [host] ссылки
+	results chan data.CheckResult
+}
+
+// task одна проверка, выданная исполнителю
+type task struct {
+	link    data.Link
+	host    string
+	results chan data.CheckResult
+}
+
+// NewEngine создаёт движок и запускает cfg.Workers исполнителей
+func NewEngine(cfg Config) *Engine {
+
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultConfig().Workers
+	}
+
+	proxy, err := data.ParseProxy(cfg.Proxy)
+	if err != nil {
+		fmt.Printf("%v - проверки пойдут напрямую\n", err)
+	}
+	rules := parseProxyRules(cfg.ProxyRules)
+
+	e := &Engine{
+		cfg:        cfg,
+		egress:     newEgressPolicy(cfg),
+		proxy:      proxy,
+		proxyRules: rules,
+		checkers:   make(map[string]Checker),
+		inFlight:   make(map[string]int),
+		done:       make(chan struct{}),
+	}
+
+	e.cond = sync.NewCond(&e.mu)
+	e.transport = e.newTransport(nil)
+
+	// у каждого профиля TLS свой транспорт, чтобы соединения с сертификатом
+	// клиента не достались ссылкам без профиля
+	e.tlsConfigs = loadTLSProfiles(cfg.TLSProfiles)
+	e.transports = make(map[string]*http.Transport, len(e.tlsConfigs))
+	for name, tlsConfig := range e.tlsConfigs {
+		e.transports[name] = e.newTransport(tlsConfig)
+	}
+
+	// встроенные проверки
+	e.Register("http", httpChecker{e})
+	e.Register("https", httpChecker{e})
+	e.Register("tcp", tcpChecker{e})
+	e.Register("dns", dnsChecker{e})
+	e.Register("ws", wsChecker{e})
+	e.Register("wss", wsChecker{e})
+	grpc := newGRPCChecker(e)
+	e.Register("grpc", grpc)
+	e.Register("grpcs", grpc)
+
+	for i := 0; i < cfg.Workers; i++ {
+		go e.worker()
+	}
+
+	return e
+}
+
+// Run проверяет набор ссылок и собирает результаты,
+// onResult (если задан) вызывается по мере готовности каждого результата
+func (e *Engine) Run(links []data.Link, onResult func(data.CheckResult)) map[string]data.CheckResult {
+
+	return e.RunContext(context.Background(), links, onResult)
+}
+
+// RunContext как Run, но при отмене ctx ещё не начатые проверки набора снимаются
+// с очереди, а возвращаются результаты, собранные к этому моменту.
+// Если движок закрыт, непроверенные ссылки получают результат с ошибкой, а начатые проверки дорабатывают
+func (e *Engine) RunContext(ctx context.Context, links []data.Link, onResult func(data.CheckResult)) map[string]data.CheckResult {
+
+	results := make(map[string]data.CheckResult)
+	if len(links) == 0 {
+		return results
+	}
+
+	b := &batch{
+		queue:   make(map[string][]data.Link),
+		results: make(chan data.CheckResult, len(links)),
+	}
+	for _, link := range links {
+		host := hostKey(link.Url)
+		if _, exists := b.queue[host]; !exists {
+			b.hosts = append(b.hosts, host)
+		}
+		b.queue[host] = append(b.queue[host], link)
+	}
+
+	e.mu.Lock()
+	e.batches = append(e.batches, b)
+	e.mu.Unlock()
+	e.cond.Broadcast()
+
+	// собираем результаты
+	done := e.done
+	for i := 0; i < len(links); {
+		select {
+		case res := <-b.results:
+			i++
+			results[res.Url] = res
+			if onResult != nil {
+				onResult(res)
+			}
+		case <-ctx.Done():
+			e.cancel(b)
+			return results
+		case <-done:
+			// исполнителей больше нет, ждать снятые с очереди ссылки некому
+			done = nil
+			for _, link := range e.cancel(b) {
+				b.results <- closedResult(link)
+			}
+		}
+	}
+
+	return results
+}
+
+// closedResult результат ссылки, которую не успели проверить до закрытия движка
+func closedResult(link data.Link) data.CheckResult {
+
+	res := newResult(link)
+	res.Protocol = schemeOf(link.Url)
+	res.ErrorKind = data.ErrorKindOther
+	res.Error = "проверка не выполнена: движок остановлен"
+	res.Settle()
+
+	return res
+}
+
+// cancel снимает с очереди непроверенные ссылки набора и возвращает их; идущие проверки
+// доработают и положат результаты в буфер набора
+func (e *Engine) cancel(b *batch) []data.Link {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var pending []data.Link
+	for _, host := range b.hosts {
+		pending = append(pending, b.queue[host]...)
+	}
+	b.hosts = nil
+	b.queue = make(map[string][]data.Link)
+
+	for i, have := range e.batches {
+		if have != b {
+			continue
+		}
+		e.batches = append(e.batches[:i], e.batches[i+1:]...)
+		if i < e.next {
+			e.next--
+		}
+		if len(e.batches) > 0 {
+			e.next %= len(e.batches)
+		} else {
+			e.next = 0
+		}
+		break
+	}
+
+	return pending
+}
+
+// Close останавливает исполнителей после завершения текущих проверок;
+// идущие вызовы Run получают результаты начатых проверок и возвращаются
+func (e *Engine) Close() {
+
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.done)
+	}
+	e.mu.Unlock()
+	e.cond.Broadcast()
+}
+
+// worker берёт задания, пока движок не закрыт
+func (e *Engine) worker() {
+
+	for {
+		t, ok := e.take()
+		if !ok {
+			return
+		}
+
+		res := e.CheckLink(t.link)
+
+		e.mu.Lock()
+		e.inFlight[t.host]--
+		if e.inFlight[t.host] == 0 {
+			delete(e.inFlight, t.host)
+		}
+		e.mu.Unlock()
+		e.cond.Broadcast()
+
+		t.results <- res
+	}
+}
+
+// take ждёт и выдаёт следующее задание, обходя наборы по кругу
+// и пропуская хосты, к которым уже идёт PerHost проверок
+func (e *Engine) take() (task, bool) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for {
+		if e.closed {
+			return task{}, false
+		}
+
+		for i := range e.batches {
+			bi := (e.next + i) % len(e.batches)
+			b := e.batches[bi]
+
+			for hi, host := range b.hosts {
+				if e.cfg.PerHost > 0 && e.inFlight[host] >= e.cfg.PerHost {
+					continue
+				}
+
+				// берём ссылку и переносим хост в конец очереди набора
+				link := b.queue[host][0]
+				b.queue[host] = b.queue[host][1:]
+				b.hosts = append(b.hosts[:hi], b.hosts[hi+1:]...)
+				if len(b.queue[host]) > 0 {
+					b.hosts = append(b.hosts, host)
+				} else {
+					delete(b.queue, host)
+				}
+
+				// опустевший набор убираем, следующий поиск начинаем со следующего набора
+				if len(b.hosts) == 0 {
+					e.batches = append(e.batches[:bi], e.batches[bi+1:]...)
+					e.next = bi
+				} else {
+					e.next = bi + 1
+				}
+				if len(e.batches) > 0 {
+					e.next %= len(e.batches)
+				} else {
+					e.next = 0
+				}
+
+				e.inFlight[host]++
+
+				return task{link: link, host: host, results: b.results}, true
+			}
+		}
+
+		e.cond.Wait()
+	}
+}
+
+// hostKey имя хоста ссылки для ограничения проверок на хост
+func hostKey(link string) string {
+
+	u, err := neturl.Parse(withScheme(link))
+	if err != nil || u.Hostname() == "" {
+		return strings.ToLower(link)
+	}
+
+	return strings.ToLower(u.Hostname())
+}
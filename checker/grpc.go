@@ -0,0 +1,256 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"verifi-server/data"
+)
+
+// состояния сервиса из grpc.health.v1.HealthCheckResponse
+const (
+	HealthUnknown        = "UNKNOWN"
+	HealthServing        = "SERVING"
+	HealthNotServing     = "NOT_SERVING"
+	HealthServiceUnknown = "SERVICE_UNKNOWN"
+)
+
+// healthStatuses номера значений перечисления ServingStatus
+var healthStatuses = []string{HealthUnknown, HealthServing, HealthNotServing, HealthServiceUnknown}
+
+// grpcHealthPath метод стандартной проверки здоровья gRPC
+const grpcHealthPath = "/grpc.health.v1.Health/Check"
+
+// grpcChecker проверяет grpc://host:port/service (h2c) и grpcs://host[:port]/service (TLS)
+// вызовом grpc.health.v1.Health/Check; пустое имя сервиса - здоровье сервера в целом
+type grpcChecker struct {
+	e          *Engine
+	transports map[string]*http.Transport // map [профиль TLS] транспорт
+}
+
+// newGRPCChecker проверка gRPC со своими транспортами HTTP/2
+// (незашифрованным для grpc:// и через TLS для grpcs://) - общим и для каждого профиля TLS
+func newGRPCChecker(e *Engine) grpcChecker {
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	newTransport := func(tlsConfig *tls.Config) *http.Transport {
+		return &http.Transport{
+			DialContext:         e.dialContext,
+			TLSClientConfig:     tlsConfig,
+			Protocols:           protocols,
+			MaxIdleConnsPerHost: max(e.cfg.PerHost, 2),
+			IdleConnTimeout:     90 * time.Second,
+		}
+	}
+
+	transports := map[string]*http.Transport{"": newTransport(nil)}
+	for name, tlsConfig := range e.tlsConfigs {
+		transports[name] = newTransport(tlsConfig)
+	}
+
+	return grpcChecker{e: e, transports: transports}
+}
+
+// Check одна попытка вызова Health/Check
+func (c grpcChecker) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	res := newResult(link)
+
+	u, err := neturl.Parse(link.Url)
+	if err != nil || u.Hostname() == "" || (u.Scheme == "grpc" && u.Port() == "") {
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = "grpc ссылка записывается как grpc://хост:порт/сервис"
+		return res
+	}
+
+	target := &neturl.URL{Scheme: "http", Host: u.Host, Path: grpcHealthPath}
+	if u.Scheme == "grpcs" {
+		target.Scheme = "https"
+		if u.Port() == "" {
+			target.Host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+	service := strings.Trim(u.Path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(grpcFrame(healthRequest(service))))
+	if err != nil {
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = err.Error()
+		return res
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	transport, ok := c.transports[link.TLSProfile]
+	if !ok {
+		transport = c.transports[""]
+	}
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		res.LatencyMs = time.Since(start).Milliseconds()
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		res.TLS = inspectTLSError(err)
+		return res
+	}
+	defer resp.Body.Close()
+
+	// трейлеры доступны только после чтения тела
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBanner))
+	res.LatencyMs = time.Since(start).Milliseconds()
+	res.StatusCode = resp.StatusCode
+	res.TLS = inspectTLS(resp.TLS, u)
+	if err != nil {
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		return res
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		res.ErrorKind = data.ErrorKindStatus
+		res.Error = resp.Status
+		return res
+	}
+
+	// при ошибке сервер может прислать grpc-status сразу в заголовках
+	code := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	switch code {
+	case "0":
+	case "":
+		res.ErrorKind = data.ErrorKindOther
+		res.Error = "ответ grpc без grpc-status"
+		return res
+	default:
+		res.ErrorKind = data.ErrorKindStatus
+		res.Error = "статус grpc " + code
+		if code == "5" {
+			// NOT_FOUND: сервер не знает такого сервиса
+			res.Health = HealthServiceUnknown
+		}
+		if message != "" {
+			res.Error += ": " + message
+		}
+		return res
+	}
+
+	res.Health, err = healthStatus(body)
+	if err != nil {
+		res.ErrorKind = data.ErrorKindOther
+		res.Error = err.Error()
+		return res
+	}
+
+	switch res.Health {
+	case HealthServing:
+		res.State = data.UpStatus
+		if warning := c.e.certWarning(res.TLS); warning != "" {
+			res.Degrade(data.ReasonCertExpiring)
+			res.Warning = warning
+		}
+
+	case HealthUnknown:
+		// сервер отвечает, но о состоянии сервиса сказать не может
+		res.Degrade(data.ReasonHealthUnknown)
+		res.Warning = "состояние сервиса UNKNOWN"
+
+	default:
+		res.ErrorKind = data.ErrorKindStatus
+		res.Error = "состояние сервиса " + res.Health
+	}
+
+	return res
+}
+
+// healthRequest кодирует HealthCheckRequest{service = 1}
+func healthRequest(service string) []byte {
+
+	if service == "" {
+		return nil
+	}
+
+	msg := []byte{0x0A}
+	msg = binary.AppendUvarint(msg, uint64(len(service)))
+
+	return append(msg, service...)
+}
+
+// grpcFrame оборачивает сообщение в кадр gRPC: флаг сжатия и длина
+func grpcFrame(msg []byte) []byte {
+
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+
+	return append(frame, msg...)
+}
+
+// healthStatus достаёт ServingStatus из кадра с HealthCheckResponse{status = 1}
+func healthStatus(body []byte) (string, error) {
+
+	if len(body) < 5 {
+		return "", fmt.Errorf("пустой ответ grpc")
+	}
+	if body[0] != 0 {
+		return "", fmt.Errorf("сжатый ответ grpc не поддерживается")
+	}
+
+	size := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < size {
+		return "", fmt.Errorf("обрезанный ответ grpc")
+	}
+	msg := body[5 : 5+size]
+
+	// отсутствующее поле означает значение по умолчанию UNKNOWN
+	status := uint64(0)
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", fmt.Errorf("некорректный ответ grpc")
+		}
+		msg = msg[n:]
+
+		switch key & 7 {
+		case 0: // varint
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return "", fmt.Errorf("некорректный ответ grpc")
+			}
+			msg = msg[n:]
+			if key>>3 == 1 {
+				status = v
+			}
+		case 2: // строка или вложенное сообщение - пропускаем
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return "", fmt.Errorf("некорректный ответ grpc")
+			}
+			msg = msg[n+int(l):]
+		default:
+			return "", fmt.Errorf("некорректный ответ grpc")
+		}
+	}
+
+	if status >= uint64(len(healthStatuses)) {
+		return fmt.Sprintf("STATUS_%d", status), nil
+	}
+
+	return healthStatuses[status], nil
+}
@@ -0,0 +1,292 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"verifi-server/data"
+)
+
+// способы опроса ресурса
+const (
+	MethodHead = "head" // сначала HEAD, при 405/501 - GET
+	MethodGet  = "get"  // сразу GET
+)
+
+// newTransport общий транспорт движка: соединения переиспользуются между проверками;
+// tlsConfig - настройки профиля TLS (nil - по умолчанию)
+func (e *Engine) newTransport(tlsConfig *tls.Config) *http.Transport {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// прокси выбирается для каждой попытки, туннель к нему открывает сам транспорт
+	transport.Proxy = requestProxy
+	transport.DialContext = e.dialTransport
+	transport.MaxIdleConns = e.cfg.Workers
+	transport.MaxIdleConnsPerHost = max(e.cfg.PerHost, 2)
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return transport
+}
+
+// httpChecker проверяет http и https ссылки через общий транспорт движка
+type httpChecker struct {
+	e *Engine
+}
+
+// Check одна попытка проверки http ссылки
+func (c httpChecker) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	e := c.e
+	res := newResult(link)
+
+	var policy data.Policy
+	if link.Policy != nil {
+		policy = *link.Policy
+	}
+
+	var hops []data.RedirectHop
+	client := e.client(e.transportFor(link.TLSProfile), link, policy, &hops)
+
+	// добавляем http:// если отсутствует
+	url := withScheme(link.Url)
+
+	start := time.Now()
+
+	// HEAD не тянет тело; если сервер его не поддерживает или тело нужно
+	// для проверок содержимого - идём GET. Метод, заданный в ссылке, не меняем
+	withBody := len(link.Assertions) > 0
+	method := http.MethodGet
+	if e.cfg.Method != MethodGet && !withBody {
+		method = http.MethodHead
+	}
+	if link.Method != "" {
+		method = strings.ToUpper(link.Method)
+	}
+
+	resp, err := e.do(ctx, client, link, method, url, !withBody)
+	if err == nil && method == http.MethodHead && link.Method == "" &&
+		(resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		e.discard(resp)
+		method = http.MethodGet
+		hops = hops[:0]
+		resp, err = e.do(ctx, client, link, method, url, true)
+	}
+
+	res.LatencyMs = time.Since(start).Milliseconds()
+	res.Method = method
+	res.Redirects = hops
+	if err != nil {
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		res.TLS = inspectTLSError(err)
+		return res
+	}
+	defer e.discard(resp)
+
+	res.StatusCode = resp.StatusCode
+	res.FinalURL = resp.Request.URL.String()
+	res.TLS = inspectTLS(resp.TLS, resp.Request.URL)
+	res.CrossDomainRedirect, res.HTTPSDowngrade = redirectFlags(url, hops, resp.Request.URL)
+
+	// по умолчанию считаем статусы 2xx и 3xx доступными
+	if !policy.ExpectedStatus.Match(resp.StatusCode) {
+		res.ErrorKind = data.ErrorKindStatus
+		res.Error = resp.Status
+		return res
+	}
+
+	// проверяем содержимое по первым MaxBodyBytes байтам тела;
+	// лишний байт показывает, что тело длиннее прочитанного
+	if withBody {
+		var body []byte
+		truncated := e.cfg.MaxBodyBytes <= 0
+		if !truncated {
+			body, err = io.ReadAll(io.LimitReader(resp.Body, e.cfg.MaxBodyBytes+1))
+			if err != nil {
+				res.ErrorKind = classifyError(err)
+				res.Error = err.Error()
+				return res
+			}
+			if int64(len(body)) > e.cfg.MaxBodyBytes {
+				body, truncated = body[:e.cfg.MaxBodyBytes], true
+			}
+		}
+
+		res.Assertions = evaluate(link.Assertions, body, truncated)
+		for _, a := range res.Assertions {
+			if !a.Passed {
+				res.ErrorKind = data.ErrorKindContent
+				res.Error = a.Reason
+				return res
+			}
+		}
+	}
+
+	res.State = data.UpStatus
+
+	// доступен, но сертификат скоро истечёт
+	if warning := e.certWarning(res.TLS); warning != "" {
+		res.Degrade(data.ReasonCertExpiring)
+		res.Warning = warning
+	}
+
+	return res
+}
+
+// client HTTP клиент поверх транспорта с правилами перенаправлений из policy;
+// каждое пройденное перенаправление записывается в hops. При переходе на другой хост
+// секретные заголовки ссылки (X-Api-Key и т.п.) не отправляются: Authorization и Cookie
+// убирает сам http.Client, а о заголовках ссылки он не знает. Через прокси следующее звено
+// откроет прокси, поэтому его хост сверяется с правилами исходящих соединений здесь
+func (e *Engine) client(transport *http.Transport, link data.Link, policy data.Policy, hops *[]data.RedirectHop) *http.Client {
+
+	maxRedirects := 10
+	if policy.MaxRedirects > 0 {
+		maxRedirects = policy.MaxRedirects
+	}
+	follow := policy.FollowRedirects == nil || *policy.FollowRedirects
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// req.Response - ответ предыдущего звена с перенаправлением на req.URL
+			*hops = append(*hops, data.RedirectHop{
+				URL:        via[len(via)-1].URL.String(),
+				StatusCode: req.Response.StatusCode,
+				Location:   req.URL.String(),
+			})
+
+			// без перенаправлений оцениваем сам ответ 3xx
+			if !follow {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("превышено число перенаправлений (%d)", maxRedirects)
+			}
+			if proxyFrom(req.Context()) != nil {
+				if ok, reason := e.egress.allowedName(req.Context(), req.URL.Hostname()); !ok {
+					return &BlockedError{Host: req.URL.Hostname(), Reason: reason}
+				}
+			}
+
+			if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+				for name := range link.Headers {
+					if data.Sensitive(name) {
+						req.Header.Del(name)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// transportFor транспорт с настройками профиля TLS (без профиля - общий)
+func (e *Engine) transportFor(profile string) *http.Transport {
+
+	if t, ok := e.transports[profile]; ok {
+		return t
+	}
+
+	return e.transport
+}
+
+// do отправляет запрос с заголовками, телом и учётными данными ссылки;
+// GET при включённом RangeGET и разрешённом ranged просит только первые MaxBodyBytes байт
+func (e *Engine) do(ctx context.Context, client *http.Client, link data.Link, method, url string, ranged bool) (*http.Response, error) {
+
+	var body io.Reader
+	if link.Body != "" {
+		body = strings.NewReader(link.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == http.MethodGet && ranged && e.cfg.RangeGET && e.cfg.MaxBodyBytes > 0 {
+		req.Header.Set("Range", "bytes=0-"+strconv.FormatInt(e.cfg.MaxBodyBytes-1, 10))
+	}
+
+	for name, value := range link.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	if link.Auth != nil {
+		if link.Auth.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+link.Auth.Token)
+		} else {
+			req.SetBasicAuth(link.Auth.Username, link.Auth.Password)
+		}
+	}
+
+	return client.Do(req)
+}
+
+// redirectFlags признаки перенаправления на другой домен и с https на http
+func redirectFlags(start string, hops []data.RedirectHop, final *neturl.URL) (bool, bool) {
+
+	if len(hops) == 0 {
+		return false, false
+	}
+
+	first, err := neturl.Parse(start)
+	if err != nil {
+		return false, false
+	}
+
+	crossDomain := siteHost(first) != siteHost(final)
+
+	// понижение: любое звено с https на http
+	downgrade := false
+	prev := first.Scheme
+	for _, hop := range hops {
+		next, err := neturl.Parse(hop.Location)
+		if err != nil {
+			continue
+		}
+		if prev == "https" && next.Scheme == "http" {
+			downgrade = true
+		}
+		prev = next.Scheme
+	}
+
+	return crossDomain, downgrade
+}
+
+// siteHost имя хоста без www. для сравнения доменов
+func siteHost(u *neturl.URL) string {
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// discard дочитывает не больше MaxBodyBytes тела и закрывает его:
+// полностью прочитанное тело позволяет вернуть соединение в пул транспорта
+func (e *Engine) discard(resp *http.Response) {
+
+	io.CopyN(io.Discard, resp.Body, e.cfg.MaxBodyBytes)
+	resp.Body.Close()
+}
+
+// withScheme добавляет http:// если схема отсутствует
+func withScheme(url string) string {
+
+	if !strings.Contains(url, "://") {
+		return "http://" + url
+	}
+
+	return url
+}
@@ -0,0 +1,390 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"verifi-server/data"
+)
+
+// ProxyError не удалось подключиться к прокси или пройти через него
+type ProxyError struct {
+	Proxy string // адрес прокси без пароля
+	Err   error
+}
+
+// Error текст ошибки
+func (e *ProxyError) Error() string {
+
+	return fmt.Sprintf("прокси %s: %v", e.Proxy, e.Err)
+}
+
+// Unwrap исходная ошибка
+func (e *ProxyError) Unwrap() error {
+
+	return e.Err
+}
+
+// proxyRule прокси для домена и его поддоменов (nil - напрямую)
+type proxyRule struct {
+	domain string
+	proxy  *neturl.URL
+}
+
+// proxyKey ключ контекста с прокси текущей попытки
+type proxyKey struct{}
+
+// withProxy контекст попытки с выбранным прокси
+func withProxy(ctx context.Context, proxy *neturl.URL) context.Context {
+
+	if proxy == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// proxyFrom прокси попытки из контекста (nil - напрямую)
+func proxyFrom(ctx context.Context) *neturl.URL {
+
+	proxy, _ := ctx.Value(proxyKey{}).(*neturl.URL)
+
+	return proxy
+}
+
+// requestProxy выбор прокси для http транспорта: тот, что выбран для попытки
+func requestProxy(req *http.Request) (*neturl.URL, error) {
+
+	return proxyFrom(req.Context()), nil
+}
+
+// parseProxyRules разбирает правила вида домен=прокси (прокси "direct" - напрямую)
+func parseProxyRules(rules []string) []proxyRule {
+
+	var parsed []proxyRule
+
+	for _, rule := range rules {
+		domain, raw, ok := strings.Cut(rule, "=")
+		domain = strings.TrimSuffix(strings.TrimLeft(strings.ToLower(strings.TrimSpace(domain)), "*."), ".")
+		proxy, err := data.ParseProxy(strings.TrimSpace(raw))
+		if !ok || domain == "" || err != nil {
+			fmt.Printf("некорректное правило прокси %q пропущено\n", rule)
+			continue
+		}
+
+		parsed = append(parsed, proxyRule{domain: domain, proxy: proxy})
+	}
+
+	return parsed
+}
+
+// proxyFor выбирает прокси для ссылки: прокси самой ссылки (или запроса),
+// затем первое подходящее правило для хоста, затем общий прокси.
+// Прокси ссылки допускается, только если это один из настроенных (см. HasProxy).
+// DNS ссылки всегда разрешаются напрямую
+func (e *Engine) proxyFor(link data.Link) (*neturl.URL, error) {
+
+	if schemeOf(link.Url) == "dns" {
+		return nil, nil
+	}
+
+	if link.Proxy != "" {
+		proxy, err := data.ParseProxy(link.Proxy)
+		if err != nil || proxy == nil {
+			return proxy, err
+		}
+		if !e.HasProxy(link.Proxy) {
+			return nil, &BlockedError{Host: proxy.Host, Reason: "прокси не из настроенных (VERIFI_PROXY, VERIFI_PROXY_RULES)"}
+		}
+		return proxy, nil
+	}
+
+	host := hostKey(link.Url)
+	for _, rule := range e.proxyRules {
+		if domainMatch(host, []string{rule.domain}) {
+			return rule.proxy, nil
+		}
+	}
+
+	return e.proxy, nil
+}
+
+// HasProxy сообщает, что прокси из запроса допустим: direct или один из настроенных
+// в VERIFI_PROXY и VERIFI_PROXY_RULES (сверяются схема, хост и порт, логин и пароль могут быть свои).
+// Иначе через произвольный прокси запрос добрался бы до того, что закрыто правилами исходящих соединений
+func (e *Engine) HasProxy(raw string) bool {
+
+	proxy, err := data.ParseProxy(raw)
+	if err != nil {
+		return false
+	}
+	if proxy == nil {
+		return true
+	}
+
+	same := func(configured *neturl.URL) bool {
+		return configured != nil && configured.Scheme == proxy.Scheme && strings.EqualFold(configured.Host, proxy.Host)
+	}
+
+	if same(e.proxy) {
+		return true
+	}
+	for _, rule := range e.proxyRules {
+		if same(rule.proxy) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialContext устанавливает исходящие соединения проверок:
+// через прокси, выбранный для попытки, или напрямую по правилам исходящих соединений
+func (e *Engine) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	proxy := proxyFrom(ctx)
+	if proxy == nil {
+		return e.dialDirect(ctx, network, addr)
+	}
+
+	conn, err := e.dialProxy(ctx, proxy, addr)
+	if err != nil {
+		return nil, &ProxyError{Proxy: proxy.Redacted(), Err: err}
+	}
+
+	return conn, nil
+}
+
+// dialTransport устанавливает соединения http транспорта. Туннель к прокси транспорт
+// открывает сам, и к адресу прокси попытки он подключается без сверки с правилами
+// исходящих соединений, как и dialProxy: прокси обычно во внутренней сети
+func (e *Engine) dialTransport(ctx context.Context, network, addr string) (net.Conn, error) {
+
+	if proxy := proxyFrom(ctx); proxy != nil && strings.EqualFold(addr, proxyAddr(proxy)) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return e.dialDirect(ctx, network, addr)
+}
+
+// proxyAddr адрес прокси host:port (без порта - стандартный для схемы)
+func proxyAddr(proxy *neturl.URL) string {
+
+	port := proxy.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[proxy.Scheme]
+		if port == "" {
+			port = "1080"
+		}
+	}
+
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+// dialProxy подключается к прокси и открывает через него туннель к addr
+// (CONNECT для http прокси, команда CONNECT SOCKS5 для socks5); имя хоста
+// разрешает сам прокси. Прокси всегда из настроенных (см. proxyFor), им доверяет
+// администратор, поэтому соединение с ним правилами исходящих соединений не сверяется
+func (e *Engine) dialProxy(ctx context.Context, proxy *neturl.URL, addr string) (net.Conn, error) {
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr(proxy))
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	switch proxy.Scheme {
+	case "socks5", "socks5h":
+		err = socksConnect(conn, proxy.User, addr)
+	default:
+		conn, err = httpConnect(conn, proxy.User, addr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// дальше сроки выставляет сама проверка
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// bufferedConn соединение, чтение которого начинается с уже прочитанного в буфер
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read читает сначала из буфера
+func (c bufferedConn) Read(p []byte) (int, error) {
+
+	return c.r.Read(p)
+}
+
+// httpConnect открывает туннель запросом CONNECT
+func httpConnect(conn net.Conn, user *neturl.Userinfo, addr string) (net.Conn, error) {
+
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if user != nil {
+		password, _ := user.Password()
+		req += "Proxy-Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)) + "\r\n"
+	}
+	req += "\r\n"
+
+	if _, err := io.WriteString(conn, req); err != nil {
+		return conn, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return conn, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+
+	// сервис мог успеть прислать приветствие, и оно уже в буфере
+	return bufferedConn{Conn: conn, r: r}, nil
+}
+
+// ответы SOCKS5 сервера на команду (RFC 1928)
+var socksReplies = []string{
+	"succeeded",
+	"general SOCKS server failure",
+	"connection not allowed by ruleset",
+	"network unreachable",
+	"host unreachable",
+	"connection refused",
+	"TTL expired",
+	"command not supported",
+	"address type not supported",
+}
+
+// socksConnect открывает туннель по SOCKS5 с проверкой логина и пароля (RFC 1929), если они заданы
+func socksConnect(conn net.Conn, user *neturl.Userinfo, addr string) error {
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("некорректный порт %q", portStr)
+	}
+
+	// приветствие: без аутентификации или логин/пароль
+	methods := []byte{0x00}
+	if user != nil {
+		methods = []byte{0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return errors.New("это не SOCKS5 прокси")
+	}
+
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		password, _ := user.Password()
+		auth := []byte{0x01, byte(len(user.Username()))}
+		auth = append(auth, user.Username()...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errors.New("SOCKS5 прокси не принял логин и пароль")
+		}
+	default:
+		return errors.New("SOCKS5 прокси требует неподдерживаемый способ входа")
+	}
+
+	// команда CONNECT: адрес IP или имя, которое разрешит прокси
+	req := []byte{0x05, 0x01, 0x00}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.Is4() {
+		req = append(append(req, 0x01), ip.AsSlice()...)
+	} else if err == nil {
+		req = append(append(req, 0x04), ip.AsSlice()...)
+	} else {
+		if len(host) > 255 {
+			return errors.New("имя хоста слишком длинное для SOCKS5")
+		}
+		req = append(append(req, 0x03, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		if int(header[1]) < len(socksReplies) {
+			return fmt.Errorf("SOCKS5 CONNECT %s: %s", addr, socksReplies[header[1]])
+		}
+		return fmt.Errorf("SOCKS5 CONNECT %s: ошибка %d", addr, header[1])
+	}
+
+	// адрес, с которого подключился прокси, нам не нужен
+	var skip int
+	switch header[3] {
+	case 0x01:
+		skip = 4
+	case 0x04:
+		skip = 16
+	case 0x03:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0])
+	default:
+		return errors.New("некорректный ответ SOCKS5")
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+
+	return err
+}
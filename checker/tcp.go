@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"regexp"
+	"time"
+
+	"verifi-server/data"
+)
+
+// maxBanner сколько байт приветствия сервиса читать и хранить
+const maxBanner = 4 << 10
+
+// tcpChecker проверяет доступность порта tcp://host:port,
+// при заданных send/expect - отправляет строку и сверяет ответ с выражением
+type tcpChecker struct {
+	e *Engine
+}
+
+// Check одна попытка подключения к порту
+func (c tcpChecker) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	res := newResult(link)
+
+	u, err := neturl.Parse(link.Url)
+	if err != nil || u.Hostname() == "" || u.Port() == "" {
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = "tcp ссылка записывается как tcp://хост:порт"
+		return res
+	}
+
+	// время подключения
+	start := time.Now()
+	conn, err := c.e.dialContext(ctx, "tcp", u.Host)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	if link.Expect == "" && link.Send == "" {
+		res.State = data.UpStatus
+		return res
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if link.Send != "" {
+		if _, err := conn.Write([]byte(link.Send)); err != nil {
+			res.ErrorKind = classifyError(err)
+			res.Error = err.Error()
+			return res
+		}
+	}
+
+	banner, err := readExpect(conn, link.Expect)
+	res.Banner = string(banner)
+	if err != nil {
+		res.ErrorKind = data.ErrorKindContent
+		res.Error = err.Error()
+		return res
+	}
+
+	res.State = data.UpStatus
+
+	return res
+}
+
+// readExpect читает ответ, пока он не совпадёт с expect, не закончится
+// или не истечёт время; без expect достаточно первой порции данных
+func readExpect(conn net.Conn, expect string) ([]byte, error) {
+
+	var re *regexp.Regexp
+	if expect != "" {
+		var err error
+		if re, err = regexp.Compile(expect); err != nil {
+			return nil, fmt.Errorf("некорректное ожидание expect %q", expect)
+		}
+	}
+
+	buf := make([]byte, 0, 512)
+	chunk := make([]byte, 512)
+
+	for len(buf) < maxBanner {
+		n, err := conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+
+		if re == nil && len(buf) > 0 || re != nil && re.Match(buf) {
+			return buf, nil
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if re == nil {
+		return buf, fmt.Errorf("нет ответа")
+	}
+
+	return buf, fmt.Errorf("ответ не совпадает с %q", expect)
+}
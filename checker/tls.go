@@ -0,0 +1,191 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"verifi-server/data"
+)
+
+// inspectTLS собирает сведения о сертификатах из состояния соединения
+func inspectTLS(state *tls.ConnectionState, finalURL *url.URL) *data.TLSInfo {
+
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	// имя, заданное профилем TLS, важнее хоста ссылки
+	name := finalURL.Hostname()
+	if state.ServerName != "" {
+		name = state.ServerName
+	}
+
+	info := certInfo(state.PeerCertificates)
+	info.Version = tls.VersionName(state.Version)
+	info.HostnameMismatch = state.PeerCertificates[0].VerifyHostname(name) != nil
+
+	return info
+}
+
+// inspectTLSError собирает сведения о сертификатах из ошибки их проверки
+func inspectTLSError(err error) *data.TLSInfo {
+
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) || len(certErr.UnverifiedCertificates) == 0 {
+		return nil
+	}
+
+	info := certInfo(certErr.UnverifiedCertificates)
+
+	var hostnameErr x509.HostnameError
+	info.HostnameMismatch = errors.As(err, &hostnameErr)
+
+	return info
+}
+
+// certInfo описывает цепочку сертификатов начиная с конечного
+func certInfo(chain []*x509.Certificate) *data.TLSInfo {
+
+	now := time.Now()
+	info := &data.TLSInfo{
+		DaysLeft: daysLeft(chain[0].NotAfter, now),
+	}
+
+	for _, cert := range chain {
+		c := data.CertInfo{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			SANs:     cert.DNSNames,
+			NotAfter: cert.NotAfter,
+			DaysLeft: daysLeft(cert.NotAfter, now),
+		}
+		for _, ip := range cert.IPAddresses {
+			c.SANs = append(c.SANs, ip.String())
+		}
+
+		info.Chain = append(info.Chain, c)
+		info.DaysLeft = min(info.DaysLeft, c.DaysLeft)
+	}
+
+	// самоподписанный: один сертификат, подписанный своим же ключом
+	leaf := chain[0]
+	info.SelfSigned = len(chain) == 1 && leaf.CheckSignatureFrom(leaf) == nil
+
+	return info
+}
+
+// daysLeft сколько полных дней осталось до notAfter (отрицательное - уже истёк)
+func daysLeft(notAfter, now time.Time) int {
+
+	return int(notAfter.Sub(now).Hours() / 24)
+}
+
+// certWarning предупреждение о скором истечении сертификата или пустая строка
+func (e *Engine) certWarning(info *data.TLSInfo) string {
+
+	if info == nil || e.cfg.CertWarnDays <= 0 || info.DaysLeft > e.cfg.CertWarnDays {
+		return ""
+	}
+
+	return fmt.Sprintf("сертификат истекает через %d дн.", info.DaysLeft)
+}
+
+// версии TLS для min_version профиля
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSProfile именованные настройки TLS для ресурсов с частным удостоверяющим центром
+// или проверкой сертификата клиента
+type TLSProfile struct {
+	CAFile     string `json:"ca_file,omitempty"`     // PEM с сертификатами УЦ (в дополнение к системным)
+	CertFile   string `json:"cert_file,omitempty"`   // PEM с сертификатом клиента
+	KeyFile    string `json:"key_file,omitempty"`    // PEM с ключом клиента
+	MinVersion string `json:"min_version,omitempty"` // минимальная версия TLS: 1.0, 1.1, 1.2, 1.3
+	ServerName string `json:"server_name,omitempty"` // имя для SNI и проверки сертификата вместо хоста ссылки
+}
+
+// load собирает настройки TLS по профилю
+func (p TLSProfile) load() (*tls.Config, error) {
+
+	cfg := &tls.Config{ServerName: p.ServerName}
+
+	if p.CAFile != "" {
+		pem, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("в %s нет сертификатов PEM", p.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if p.CertFile != "" || p.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if p.MinVersion != "" {
+		version, ok := tlsVersions[p.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("неизвестная версия TLS %q", p.MinVersion)
+		}
+		cfg.MinVersion = version
+	}
+
+	return cfg, nil
+}
+
+// loadTLSProfiles собирает настройки по всем профилям; профиль с ошибкой пропускается
+func loadTLSProfiles(profiles map[string]TLSProfile) map[string]*tls.Config {
+
+	configs := make(map[string]*tls.Config, len(profiles))
+	for name, p := range profiles {
+		cfg, err := p.load()
+		if err != nil {
+			fmt.Printf("профиль TLS %q пропущен: %v\n", name, err)
+			continue
+		}
+		configs[name] = cfg
+	}
+
+	return configs
+}
+
+// HasTLSProfile сообщает, что профиль TLS с таким именем загружен
+func (e *Engine) HasTLSProfile(name string) bool {
+
+	_, ok := e.tlsConfigs[name]
+
+	return ok
+}
+
+// tlsConfig настройки TLS профиля для соединения с host (без профиля - по умолчанию)
+func (e *Engine) tlsConfig(profile, host string) *tls.Config {
+
+	cfg := &tls.Config{}
+	if c, ok := e.tlsConfigs[profile]; ok {
+		cfg = c.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	return cfg
+}
@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"verifi-server/data"
+)
+
+// phaseTracer замеряет фазы попытки через httptrace: разрешение имени и подключение
+// отмечают и http транспорт, и net.Dialer, поэтому фазы видны у всех проверок,
+// а TLS и первый байт ответа - у http, https и grpc
+type phaseTracer struct {
+	start time.Time
+
+	dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tls, ttfb          time.Duration
+	reused                           bool
+
+	mu sync.Mutex // соединение транспорт может устанавливать в своей горутине
+}
+
+// newPhaseTracer начинает замер и возвращает контекст с ним
+func newPhaseTracer(ctx context.Context) (*phaseTracer, context.Context) {
+
+	t := &phaseTracer{start: time.Now()}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.add(&t.dns, t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.add(&t.connect, t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.add(&t.tls, t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = t.reused || info.Reused
+			t.mu.Unlock()
+		},
+		// после перенаправлений - первый байт последнего ответа
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.ttfb = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	}
+
+	return t, httptrace.WithClientTrace(ctx, trace)
+}
+
+// mark отмечает начало фазы
+func (t *phaseTracer) mark(at *time.Time) {
+
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+// add добавляет длительность фазы (при перенаправлениях фазы складываются)
+func (t *phaseTracer) add(d *time.Duration, from time.Time) {
+
+	t.mu.Lock()
+	if !from.IsZero() {
+		*d += time.Since(from)
+	}
+	t.mu.Unlock()
+}
+
+// timings итог замера на момент окончания попытки
+func (t *phaseTracer) timings() *data.Timings {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &data.Timings{
+		DNSMs:     ms(t.dns),
+		ConnectMs: ms(t.connect),
+		TLSMs:     ms(t.tls),
+		TTFBMs:    ms(t.ttfb),
+		TotalMs:   ms(time.Since(t.start)),
+		Reused:    t.reused,
+	}
+}
+
+// ms длительность в миллисекундах с точностью до десятых
+func ms(d time.Duration) float64 {
+
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
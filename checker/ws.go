@@ -0,0 +1,287 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"time"
+
+	"verifi-server/data"
+)
+
+// websocketGUID константа из RFC 6455 для вычисления Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// коды кадров WebSocket
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+)
+
+// wsChecker проверяет ws:// и wss:// ссылки рукопожатием Upgrade,
+// при заданных send/expect - отправляет сообщение и сверяет ответ с выражением
+type wsChecker struct {
+	e *Engine
+}
+
+// Check одна попытка рукопожатия
+func (c wsChecker) Check(ctx context.Context, link data.Link) data.CheckResult {
+
+	res := newResult(link)
+
+	u, err := neturl.Parse(link.Url)
+	if err != nil || u.Hostname() == "" {
+		res.ErrorKind = data.ErrorKindInvalid
+		res.Error = "websocket ссылка записывается как ws://хост[:порт]/путь"
+		return res
+	}
+
+	start := time.Now()
+
+	conn, err := c.dial(ctx, u, link.TLSProfile, &res)
+	if err != nil {
+		res.LatencyMs = time.Since(start).Milliseconds()
+		res.ErrorKind = classifyError(err)
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	code, err := handshake(conn, reader, u)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	res.StatusCode = code
+	if err != nil {
+		res.ErrorKind = data.ErrorKindStatus
+		if code == 0 {
+			res.ErrorKind = classifyError(err)
+		}
+		res.Error = err.Error()
+		return res
+	}
+
+	if link.Send != "" || link.Expect != "" {
+		reply, err := exchange(conn, reader, link.Send, link.Expect)
+		res.Banner = reply
+		if err != nil {
+			res.ErrorKind = data.ErrorKindContent
+			res.Error = err.Error()
+			return res
+		}
+	}
+
+	// вежливо закрываем соединение
+	writeFrame(conn, wsClose, nil)
+
+	res.State = data.UpStatus
+
+	return res
+}
+
+// dial подключается к хосту, для wss - с TLS по профилю и записью сведений о сертификате
+func (c wsChecker) dial(ctx context.Context, u *neturl.URL, profile string, res *data.CheckResult) (net.Conn, error) {
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+	}
+
+	conn, err := c.e.dialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil || u.Scheme != "wss" {
+		return conn, err
+	}
+
+	tlsConn := tls.Client(conn, c.e.tlsConfig(profile, u.Hostname()))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		res.TLS = inspectTLSError(err)
+		return nil, err
+	}
+
+	state := tlsConn.ConnectionState()
+	res.TLS = inspectTLS(&state, u)
+
+	return tlsConn, nil
+}
+
+// handshake отправляет запрос Upgrade и проверяет ответ 101 с верным Sec-WebSocket-Accept
+func handshake(conn net.Conn, reader *bufio.Reader, u *neturl.URL) (int, error) {
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	path := u.RequestURI()
+
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", path, u.Host, key)
+	if _, err := io.WriteString(conn, req); err != nil {
+		return 0, err
+	}
+
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return resp.StatusCode, fmt.Errorf("переход на websocket отклонён: %s", resp.Status)
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return resp.StatusCode, fmt.Errorf("некорректный Sec-WebSocket-Accept")
+	}
+
+	return resp.StatusCode, nil
+}
+
+// exchange отправляет сообщение (если задано) и читает сообщения, пока очередное не совпадёт с expect
+func exchange(conn net.Conn, reader *bufio.Reader, send, expect string) (string, error) {
+
+	var re *regexp.Regexp
+	if expect != "" {
+		var err error
+		if re, err = regexp.Compile(expect); err != nil {
+			return "", fmt.Errorf("некорректное ожидание expect %q", expect)
+		}
+	}
+
+	if send != "" {
+		if err := writeFrame(conn, wsText, []byte(send)); err != nil {
+			return "", err
+		}
+	}
+
+	var last string
+	for {
+		opcode, payload, err := readFrame(reader)
+		if err != nil {
+			if re == nil && last == "" {
+				return "", fmt.Errorf("нет ответа: %v", err)
+			}
+			return last, fmt.Errorf("ответ не совпадает с %q", expect)
+		}
+
+		switch opcode {
+		case wsPing:
+			writeFrame(conn, 0xA, payload) // pong
+			continue
+		case wsClose:
+			return last, fmt.Errorf("соединение закрыто до подходящего ответа")
+		}
+
+		last = string(payload)
+		if re == nil || re.MatchString(last) {
+			return last, nil
+		}
+	}
+}
+
+// writeFrame пишет один кадр клиента (клиентские кадры обязаны быть замаскированы)
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+
+	frame := []byte{0x80 | opcode}
+
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := w.Write(frame)
+
+	return err
+}
+
+// readFrame читает одно сообщение сервера, склеивая фрагменты
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+
+	var opcode byte
+	var message []byte
+
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, nil, err
+		}
+
+		fin := header[0]&0x80 != 0
+		if op := header[0] & 0x0F; op != 0 {
+			opcode = op
+		}
+
+		n := uint64(header[1] & 0x7F)
+		switch n {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return 0, nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return 0, nil, err
+			}
+			n = binary.BigEndian.Uint64(ext)
+		}
+		if n > maxBanner {
+			return 0, nil, fmt.Errorf("слишком большое сообщение (%d байт)", n)
+		}
+
+		var mask []byte
+		if header[1]&0x80 != 0 {
+			mask = make([]byte, 4)
+			if _, err := io.ReadFull(r, mask); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 0, nil, err
+		}
+		if mask != nil {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
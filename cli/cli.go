@@ -24,13 +24,16 @@ func showHelp(port string) {
 	fmt.Println("  help     - Показать эту справку")
 	fmt.Println("")
 	fmt.Println("Эндпоинты API:")
-	fmt.Println("  POST /api/check    - Проверить доступность ссылок")
+	fmt.Println("  POST /api/check    - Проверить доступность ссылок (?async=true - в фоне)")
+	fmt.Println("  GET  /api/check/N  - Ход фоновой проверки или готовый набор N")
 	fmt.Println("  POST /api/report   - Сгенерировать PDF отчет")
+	fmt.Println("  POST /api/monitors - Завести монитор: проверка набора по расписанию")
+	fmt.Println("  GET  /api/monitors - Мониторы с последним состоянием (/N - один, /N/runs - история)")
 	fmt.Println("")
 }
 
 // RunCLI позволяет управлять приложением из консоли
-func RunCLI(port string) {
+func RunCLI(port string, handlers *api.Handlers, store data.Store, monitors data.MonitorStore) {
 
 	// канал для остановки WaitForShutdownSignal при остановке сервера
 	done := make(chan struct{})
@@ -61,6 +64,16 @@ func RunCLI(port string) {
 				fmt.Println("👋 Выходим из программы.")
 			}
 
+			// дожидаемся идущих прогонов мониторов, чтобы они успели сохраниться
+			handlers.StopMonitors()
+
+			if err := store.Close(); err != nil {
+				fmt.Printf("Ошибка закрытия хранилища: %v\n", err)
+			}
+			if err := monitors.Close(); err != nil {
+				fmt.Printf("Ошибка закрытия хранилища мониторов: %v\n", err)
+			}
+
 			os.Exit(0)
 
 		case "restart": // Graceful shutdown серверу и новый запуск
@@ -82,9 +95,9 @@ func RunCLI(port string) {
 				// проверяем и дообрабатываем, если остались, ссылки после shutdown
 				if len(data.SDCache.CacheLinks) != 0 {
 					for i := range data.SDCache.CacheLinks {
-						api.CacheLinksCheck(data.SDCache.CacheLinks[i])
+						handlers.CacheLinksCheck(data.SDCache.CacheLinks[i])
 					}
-					data.SDCache.CacheLinks = make([][]string, 0)
+					data.SDCache.CacheLinks = make([][]data.Link, 0)
 				}
 
 				// Проверять и дообрабатывать, если остались, номера запросов после shutdown,
@@ -99,6 +112,15 @@ func RunCLI(port string) {
 
 			fmt.Printf("✅ Сервер работает на http://localhost:%s\n", port)
 
+			stats := store.Stats()
+			fmt.Printf("📦 Наборов в хранилище: %d, ссылок: %d, следующий номер: %d\n", stats.Sets, stats.Links, stats.NextID)
+			for _, state := range data.Statuses {
+				if n := stats.States[state]; n > 0 {
+					fmt.Printf("   %s: %d\n", state, n)
+				}
+			}
+			fmt.Printf("⏱️ Мониторов: %d\n", len(monitors.ListMonitors()))
+
 		case "help":
 
 			showHelp(port)
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Assertion проверка содержимого ответа; задаётся ровно одно из условий
+type Assertion struct {
+	Contains    string          `json:"contains,omitempty"`     // тело должно содержать строку
+	NotContains string          `json:"not_contains,omitempty"` // тело не должно содержать строку
+	Regex       string          `json:"regex,omitempty"`        // тело должно соответствовать выражению
+	JSONPath    string          `json:"json_path,omitempty"`    // путь к полю JSON, например data.items[0].status
+	Equals      json.RawMessage `json:"equals,omitempty"`       // ожидаемое значение поля по json_path
+}
+
+// AssertionResult итог проверки содержимого
+type AssertionResult struct {
+	Assertion
+	Passed bool   `json:"passed"`           // условие выполнено
+	Reason string `json:"reason,omitempty"` // почему условие не выполнено
+}
+
+// Validate проверяет, что условие задано ровно одно и записано корректно
+func (a Assertion) Validate() error {
+
+	set := 0
+	for _, v := range []string{a.Contains, a.NotContains, a.Regex, a.JSONPath} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("в проверке содержимого должно быть ровно одно из contains, not_contains, regex, json_path")
+	}
+
+	if a.Regex != "" {
+		if _, err := regexp.Compile(a.Regex); err != nil {
+			return fmt.Errorf("некорректное выражение %q: %w", a.Regex, err)
+		}
+	}
+
+	if a.JSONPath != "" && len(a.Equals) == 0 {
+		return fmt.Errorf("для json_path %q не задано значение equals", a.JSONPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron расписание в формате cron из пяти полей: минута, час, день месяца, месяц, день недели.
+// Поле - список через запятую из *, чисел, диапазонов a-b и шагов */n или a-b/n;
+// месяцы и дни недели можно писать именами (jan, mon), воскресенье - 0 или 7.
+// Как и в классическом cron, если заданы и день месяца, и день недели, достаточно совпадения одного из них
+type Cron struct {
+	minute, hour, dom, month, dow uint64 // биты допустимых значений полей
+
+	domAny, dowAny bool // день месяца или недели не ограничен (*)
+}
+
+// cronAliases сокращённые записи расписаний
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField границы и имена значений поля
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // имена значений начиная с min
+}
+
+var (
+	cronMinute = cronField{name: "минута", min: 0, max: 59}
+	cronHour   = cronField{name: "час", min: 0, max: 23}
+	cronDom    = cronField{name: "день месяца", min: 1, max: 31}
+	cronMonth  = cronField{name: "месяц", min: 1, max: 12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	cronDow = cronField{name: "день недели", min: 0, max: 7,
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// cronHorizon насколько вперёд искать следующее срабатывание (29 февраля в понедельник бывает раз в 28 лет)
+const cronHorizon = 30
+
+// ParseCron разбирает запись расписания
+func ParseCron(expr string) (*Cron, error) {
+
+	spec := strings.ToLower(strings.TrimSpace(expr))
+	if alias, ok := cronAliases[spec]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("некорректное расписание %q: ожидается 5 полей (минута час день месяц день_недели)", expr)
+	}
+
+	var c Cron
+	var err error
+	for i, f := range []struct {
+		field cronField
+		bits  *uint64
+	}{
+		{cronMinute, &c.minute},
+		{cronHour, &c.hour},
+		{cronDom, &c.dom},
+		{cronMonth, &c.month},
+		{cronDow, &c.dow},
+	} {
+		if *f.bits, err = f.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("некорректное расписание %q: %w", expr, err)
+		}
+	}
+
+	// 7 - тоже воскресенье
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return &c, nil
+}
+
+// parse переводит поле в биты допустимых значений
+func (f cronField) parse(s string) (uint64, error) {
+
+	var bits uint64
+
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: некорректный шаг %q", f.name, part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch from, to, isRange := strings.Cut(rng, "-"); {
+		case rng == "*":
+		case isRange:
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(to); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: пустой диапазон %q", f.name, part)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			// n/шаг - от n до конца поля
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// value число или имя значения поля
+func (f cronField) value(s string) (int, error) {
+
+	for i, name := range f.names {
+		if s == name {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: значение %q вне %d-%d", f.name, s, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// Next ближайшее срабатывание строго после t (в часовом поясе t);
+// нулевое время, если расписание не срабатывает никогда (например, 30 февраля)
+func (c *Cron) Next(t time.Time) time.Time {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronHorizon, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatch(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatch совпадение дня по дню месяца и дню недели
+func (c *Cron) dayMatch(t time.Time) bool {
+
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
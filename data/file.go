@@ -0,0 +1,403 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// logFileName имя файла журнала в директории данных
+const logFileName = "results.log"
+
+// compactMinRecords с какого числа записей журнал есть смысл переписывать
+const compactMinRecords = 1000
+
+// logRecord одна запись журнала результатов
+type logRecord struct {
+	ID       int                    `json:"id"`                 // номер набора
+	Results  map[string]CheckResult `json:"results,omitempty"`  // результаты набора
+	Deleted  bool                   `json:"deleted,omitempty"`  // набор удалён
+	Reserved bool                   `json:"reserved,omitempty"` // номер зарезервирован, набор ещё не готов
+}
+
+// FileStorage хранилище результатов в append-only журнале на диске.
+// Каждый набор дописывается одной строкой JSON и сбрасывается на диск (fsync)
+// до того, как клиент получит номер; при открытии журнал проигрывается в память.
+type FileStorage struct {
+	mem     *Storage // индекс в памяти для чтения
+	path    string   // путь к журналу
+	file    *os.File // открытый на дозапись журнал
+	records int      // сколько записей в журнале
+	mu      sync.Mutex
+}
+
+// OpenFileStorage открывает (или создаёт) журнал в директории dir и восстанавливает из него наборы
+func OpenFileStorage(dir string) (*FileStorage, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию данных %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, logFileName)
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал %s: %w", path, err)
+	}
+
+	fs := &FileStorage{
+		mem:  NewStorage(),
+		path: path,
+		file: file,
+	}
+
+	fs.records, err = fs.replay()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось восстановить журнал %s: %w", path, err)
+	}
+
+	fs.compactIfNeeded()
+
+	// фиксируем в директории сам файл журнала, если он только что создан или переписан
+	if err := syncDir(dir); err != nil {
+		fs.file.Close()
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// replay читает журнал с начала и наполняет индекс; возвращает число записей в журнале
+func (fs *FileStorage) replay() (int, error) {
+
+	return replayLog(fs.file, func(line []byte) bool {
+		var rec logRecord
+		if err := json.Unmarshal(line, &rec); err != nil || rec.ID <= 0 {
+			return false
+		}
+
+		switch {
+		case rec.Deleted:
+			fs.mem.forget(rec.ID)
+		case rec.Reserved:
+			fs.mem.reserve(rec.ID)
+		default:
+			fs.mem.put(rec.ID, rec.Results)
+		}
+
+		return true
+	})
+}
+
+// replayLog передаёт apply строки журнала с начала; целые строки, которые apply
+// не смог разобрать (вернул false), пропускаются с предупреждением, а недописанная
+// последняя строка (оборванная сбоем запись) отрезается. После чтения журнал стоит в конце.
+// Возвращает число целых строк журнала
+func replayLog(file *os.File, apply func(line []byte) bool) (int, error) {
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(file)
+	var good int64 // смещение конца последней целой строки
+	number := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+
+		// строка без перевода в конце - запись, оборванная сбоем
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		number++
+		good += int64(len(line))
+
+		// повреждённая запись не должна стоить всех записей после неё
+		if record := bytes.TrimSpace(line); len(record) != 0 && !apply(record) {
+			fmt.Printf("журнал %s: пропущена повреждённая запись в строке %d\n", file.Name(), number)
+		}
+	}
+
+	// отрезаем недописанный хвост и встаём в конец
+	if err := file.Truncate(good); err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(good, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return number, nil
+}
+
+// needsCompaction журнал переписывается, когда в нём заметно больше записей, чем живых:
+// больше compactMinRecords и больше чем вдвое против того, что осталось в памяти
+func needsCompaction(records, live int) bool {
+
+	return records > compactMinRecords && records > 2*live
+}
+
+// rewriteLog заменяет журнал path (открытый как file) записями records: они пишутся
+// во временный файл рядом, который сбрасывается на диск и переименованием атомарно встаёт
+// на место журнала. Возвращает новый журнал, стоящий в конце; при ошибке прежний остаётся как был
+func rewriteLog(path string, file *os.File, records []any) (*os.File, error) {
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось переписать журнал %s: %w", path, err)
+	}
+
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return nil, fmt.Errorf("не удалось переписать журнал %s: %w", path, err)
+	}
+
+	fail := func(err error) (*os.File, error) {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("не удалось переписать журнал %s: %w", path, err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fail(err)
+		}
+		// ошибки записи вернёт Flush
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fail(err)
+	}
+
+	file.Close()
+
+	// переименование держится только после сброса директории; журнал уже новый,
+	// так что при ошибке о ней остаётся лишь сообщить
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		fmt.Printf("журнал %s переписан, но %v\n", path, err)
+	}
+
+	return tmp, nil
+}
+
+// compactIfNeeded переписывает журнал, когда удалённые наборы заметно перевесили живые:
+// при открытии и после удалений, ведь прогоны мониторов непрерывно сохраняют и удаляют наборы.
+// Вызывается под fs.mu (или до того, как хранилище стало доступно)
+func (fs *FileStorage) compactIfNeeded() {
+
+	fs.mem.mu.RLock()
+	live := len(fs.mem.data)
+	fs.mem.mu.RUnlock()
+
+	if !needsCompaction(fs.records, live) {
+		return
+	}
+	if err := fs.compact(); err != nil {
+		fmt.Printf("%v - журнал остаётся прежним\n", err)
+	}
+}
+
+// compact переписывает журнал одними живыми наборами; последний выданный номер
+// сохраняется отметкой резерва, чтобы номера удалённых наборов не выдавались повторно
+func (fs *FileStorage) compact() error {
+
+	fs.mem.mu.RLock()
+	ids := slices.Sorted(maps.Keys(fs.mem.data))
+	records := make([]any, 0, len(ids)+1)
+	for _, id := range ids {
+		records = append(records, logRecord{ID: id, Results: fs.mem.data[id]})
+	}
+	if last := fs.mem.nextID - 1; last > 0 {
+		records = append(records, logRecord{ID: last, Reserved: true})
+	}
+	fs.mem.mu.RUnlock()
+
+	file, err := rewriteLog(fs.path, fs.file, records)
+	if err != nil {
+		return err
+	}
+	fs.file = file
+	fs.records = len(records)
+
+	return nil
+}
+
+// SaveResults дописывает набор в журнал и возвращает его номер
+func (fs *FileStorage) SaveResults(results map[string]CheckResult) (int, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.mem.mu.RLock()
+	id := fs.mem.nextID
+	fs.mem.mu.RUnlock()
+
+	if err := fs.append(logRecord{ID: id, Results: results}); err != nil {
+		return 0, err
+	}
+
+	fs.mem.put(id, results)
+
+	return id, nil
+}
+
+// ReserveID дописывает в журнал резерв номера, чтобы после перезапуска он не был выдан повторно
+func (fs *FileStorage) ReserveID() (int, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.mem.mu.RLock()
+	id := fs.mem.nextID
+	fs.mem.mu.RUnlock()
+
+	if err := fs.append(logRecord{ID: id, Reserved: true}); err != nil {
+		return 0, err
+	}
+
+	fs.mem.reserve(id)
+
+	return id, nil
+}
+
+// PutResults дописывает в журнал набор под зарезервированным номером
+func (fs *FileStorage) PutResults(id int, results map[string]CheckResult) error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.append(logRecord{ID: id, Results: results}); err != nil {
+		return err
+	}
+
+	fs.mem.put(id, results)
+
+	return nil
+}
+
+// GetResults смотрит, что есть в хранилище по номеру
+func (fs *FileStorage) GetResults(id int) (map[string]CheckResult, bool) {
+
+	return fs.mem.GetResults(id)
+}
+
+// ListResults возвращает номера всех наборов по возрастанию
+func (fs *FileStorage) ListResults() []int {
+
+	return fs.mem.ListResults()
+}
+
+// DeleteResults дописывает в журнал отметку об удалении набора
+func (fs *FileStorage) DeleteResults(id int) (bool, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.mem.GetResults(id); !exists {
+		return false, nil
+	}
+
+	if err := fs.append(logRecord{ID: id, Deleted: true}); err != nil {
+		return false, err
+	}
+
+	deleted, err := fs.mem.DeleteResults(id)
+	fs.compactIfNeeded()
+
+	return deleted, err
+}
+
+// Stats возвращает сводку по хранилищу
+func (fs *FileStorage) Stats() Stats {
+
+	return fs.mem.Stats()
+}
+
+// Close закрывает журнал
+func (fs *FileStorage) Close() error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.file.Close()
+}
+
+// append записывает одну строку журнала и сбрасывает её на диск
+func (fs *FileStorage) append(rec logRecord) error {
+
+	if err := appendLog(fs.file, rec); err != nil {
+		return err
+	}
+	fs.records++
+
+	return nil
+}
+
+// appendLog дописывает запись строкой JSON в конец журнала и сбрасывает её на диск
+func appendLog(file *os.File, rec any) error {
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать запись журнала: %w", err)
+	}
+	line = append(line, '\n')
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("не удалось определить позицию в журнале: %w", err)
+	}
+
+	// при частичной записи откатываемся, чтобы следующая запись не легла за мусором
+	if _, err := file.Write(line); err != nil {
+		if truncErr := file.Truncate(offset); truncErr != nil {
+			return fmt.Errorf("не удалось записать журнал: %w, и не удалось откатить запись: %w", err, truncErr)
+		}
+		if _, seekErr := file.Seek(offset, io.SeekStart); seekErr != nil {
+			return fmt.Errorf("не удалось записать журнал: %w, и не удалось откатить запись: %w", err, seekErr)
+		}
+		return fmt.Errorf("не удалось записать журнал: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("не удалось сбросить журнал на диск: %w", err)
+	}
+
+	return nil
+}
+
+// syncDir сбрасывает на диск содержимое директории
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("не удалось сбросить директорию %s на диск: %w", dir, err)
+	}
+
+	return nil
+}
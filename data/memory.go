@@ -0,0 +1,164 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Storage структура хранилища результатов в памяти
+type Storage struct {
+	data   map[int]map[string]CheckResult // map [links_num] map [{url: result}]
+	nextID int                            // счётчик запросов
+	mu     sync.RWMutex
+}
+
+// NewStorage создаёт пустое хранилище в памяти
+func NewStorage() *Storage {
+
+	return &Storage{
+		data:   make(map[int]map[string]CheckResult),
+		nextID: 1,
+	}
+}
+
+// SaveResults сохраняет результаты запросов по номерам
+func (s *Storage) SaveResults(results map[string]CheckResult) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.data[id] = withStates(results)
+	s.nextID++
+
+	return id, nil
+}
+
+// ReserveID резервирует номер под набор, который сохранится позже
+func (s *Storage) ReserveID() (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+
+	return id, nil
+}
+
+// PutResults сохраняет набор под зарезервированным номером
+func (s *Storage) PutResults(id int, results map[string]CheckResult) error {
+
+	s.put(id, results)
+
+	return nil
+}
+
+// GetResults смотрит, что есть в хранилище по номеру
+func (s *Storage) GetResults(id int) (map[string]CheckResult, bool) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results, exists := s.data[id]
+
+	return results, exists
+}
+
+// ListResults возвращает номера всех наборов по возрастанию
+func (s *Storage) ListResults() []int {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(s.data))
+}
+
+// DeleteResults удаляет набор по номеру
+func (s *Storage) DeleteResults(id int) (bool, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.data[id]
+	delete(s.data, id)
+
+	return exists, nil
+}
+
+// Stats возвращает сводку по хранилищу
+func (s *Storage) Stats() Stats {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{
+		Sets:   len(s.data),
+		States: make(map[Status]int),
+		NextID: s.nextID,
+	}
+	for _, results := range s.data {
+		stats.Links += len(results)
+		for _, res := range results {
+			stats.States[res.State]++
+		}
+	}
+
+	return stats
+}
+
+// Close для хранилища в памяти ничего не делает
+func (s *Storage) Close() error {
+
+	return nil
+}
+
+// put кладёт набор под заданным номером и сдвигает счётчик (используется при восстановлении)
+func (s *Storage) put(id int, results map[string]CheckResult) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[id] = withStates(results)
+	if id >= s.nextID {
+		s.nextID = id + 1
+	}
+}
+
+// withStates дополняет результаты без состояния (от сторонних проверок) состоянием
+// по прежнему статусу, чтобы в хранилище оба поля были согласованы
+func withStates(results map[string]CheckResult) map[string]CheckResult {
+
+	for url, res := range results {
+		if res.State == "" {
+			res.fillState()
+			results[url] = res
+		}
+	}
+
+	return results
+}
+
+// reserve сдвигает счётчик за номер id без сохранения набора (используется при восстановлении)
+func (s *Storage) reserve(id int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id >= s.nextID {
+		s.nextID = id + 1
+	}
+}
+
+// forget удаляет набор, не трогая счётчик (используется при восстановлении)
+func (s *Storage) forget(id int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, id)
+	if id >= s.nextID {
+		s.nextID = id + 1
+	}
+}
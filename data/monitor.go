@@ -0,0 +1,140 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinMonitorInterval самый частый допустимый интервал прогонов монитора
+const MinMonitorInterval = 10 * time.Second
+
+// MonitorHistory сколько последних прогонов хранится у монитора
+const MonitorHistory = 1000
+
+// Monitor набор ссылок, который проверяется по расписанию: раз в Interval или по Cron
+type Monitor struct {
+	ID        int       `json:"id"`                 // номер монитора
+	Name      string    `json:"name,omitempty"`     // название для людей
+	Links     []Link    `json:"links"`              // ссылки с правилами проверки
+	Interval  Duration  `json:"interval,omitempty"` // интервал между прогонами, например "5m"
+	Cron      string    `json:"cron,omitempty"`     // расписание в формате cron, например "*/5 * * * *"
+	CreatedAt time.Time `json:"created_at"`         // когда монитор заведён
+}
+
+// MonitorRun итог одного прогона монитора; подробные результаты лежат в хранилище наборов под LinksNum
+type MonitorRun struct {
+	LinksNum   int               `json:"links_num,omitempty"` // номер набора с результатами прогона
+	StartedAt  time.Time         `json:"started_at"`          // начало прогона
+	DurationMs int64             `json:"duration_ms"`         // сколько длился прогон
+	State      Status            `json:"state"`               // итог прогона (см. Overall)
+	States     map[string]Status `json:"states"`              // map [{url: state}]
+	Error      string            `json:"error,omitempty"`     // почему набор не сохранён
+}
+
+// MonitorStore описывает хранилище мониторов и истории их прогонов
+type MonitorStore interface {
+	SaveMonitor(m Monitor) (Monitor, error)                 // сохраняет новый монитор и возвращает его с номером
+	GetMonitor(id int) (Monitor, bool)                      // возвращает монитор по номеру
+	ListMonitors() []Monitor                                // возвращает все мониторы по возрастанию номеров
+	DeleteMonitor(id int) (bool, error)                     // удаляет монитор с историей, false - если его не было
+	AppendRun(id int, run MonitorRun) ([]MonitorRun, error) // дописывает прогон в историю монитора и возвращает вытесненные из неё
+	Runs(id int, limit int) ([]MonitorRun, bool)            // последние limit прогонов (0 - все), новые первыми
+	Close() error                                           // освобождает ресурсы хранилища
+}
+
+// OpenMonitors открывает хранилище мониторов: при непустом dir - файловое в этой директории, иначе в памяти
+func OpenMonitors(dir string) (MonitorStore, error) {
+
+	if dir == "" {
+		return NewMonitorStorage(), nil
+	}
+
+	return OpenFileMonitorStorage(dir)
+}
+
+// Validate проверяет расписание и ссылки монитора
+func (m Monitor) Validate() error {
+
+	if len(m.Links) == 0 {
+		return fmt.Errorf("ссылок нет")
+	}
+
+	switch {
+	case m.Interval == 0 && m.Cron == "":
+		return fmt.Errorf("задайте interval или cron")
+	case m.Interval != 0 && m.Cron != "":
+		return fmt.Errorf("задаётся либо interval, либо cron")
+	case m.Interval != 0 && time.Duration(m.Interval) < MinMonitorInterval:
+		return fmt.Errorf("интервал не может быть меньше %v", MinMonitorInterval)
+	case m.Cron != "":
+		cron, err := ParseCron(m.Cron)
+		if err != nil {
+			return err
+		}
+		if cron.Next(time.Now()).IsZero() {
+			return fmt.Errorf("расписание %q никогда не срабатывает", m.Cron)
+		}
+	}
+
+	for _, link := range m.Links {
+		if err := link.Validate(); err != nil {
+			return fmt.Errorf("ссылка %s: %w", RedactURL(link.Url), err)
+		}
+	}
+
+	return nil
+}
+
+// Next время следующего прогона после after; нулевое время - прогонов больше не будет
+func (m Monitor) Next(after time.Time) time.Time {
+
+	if m.Cron == "" {
+		return after.Add(time.Duration(m.Interval))
+	}
+
+	cron, err := ParseCron(m.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return cron.Next(after)
+}
+
+// Redacted копия монитора для выдачи: без паролей, токенов и тел запросов ссылок
+func (m Monitor) Redacted() Monitor {
+
+	links := make([]Link, len(m.Links))
+	for i, link := range m.Links {
+		links[i] = link.Redacted()
+	}
+	m.Links = links
+
+	return m
+}
+
+// Overall итог прогона по состояниям ссылок - худшее из них: down, если хоть одна
+// ссылка down; unknown, если доступность какой-то ссылки не выяснена (unknown, blocked,
+// invalid); degraded, если есть ссылки в degraded; иначе up
+func Overall(states map[string]Status) Status {
+
+	if len(states) == 0 {
+		return UnknownStatus
+	}
+
+	overall := UpStatus
+	for _, state := range states {
+		switch state {
+		case UpStatus:
+		case DegradedStatus:
+			if overall == UpStatus {
+				overall = DegradedStatus
+			}
+		case DownStatus:
+			return DownStatus
+		default:
+			overall = UnknownStatus
+		}
+	}
+
+	return overall
+}
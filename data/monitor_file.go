@@ -0,0 +1,236 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// monitorLogFileName имя файла журнала мониторов в директории данных
+const monitorLogFileName = "monitors.log"
+
+// monitorRecord одна запись журнала мониторов: новый монитор, прогон, удаление
+// или резерв номера (после того как журнал переписан)
+type monitorRecord struct {
+	ID       int         `json:"id"`                 // номер монитора
+	Monitor  *Monitor    `json:"monitor,omitempty"`  // заведённый монитор
+	Run      *MonitorRun `json:"run,omitempty"`      // прогон монитора
+	Deleted  bool        `json:"deleted,omitempty"`  // монитор удалён
+	Reserved bool        `json:"reserved,omitempty"` // номер уже выдавался
+}
+
+// FileMonitorStorage хранилище мониторов в append-only журнале на диске,
+// устроенное так же, как FileStorage: запись сбрасывается на диск до ответа,
+// при открытии журнал проигрывается в память
+type FileMonitorStorage struct {
+	mem     *MonitorStorage // индекс в памяти для чтения
+	path    string          // путь к журналу
+	file    *os.File        // открытый на дозапись журнал
+	records int             // сколько записей в журнале
+	mu      sync.Mutex
+}
+
+// OpenFileMonitorStorage открывает (или создаёт) журнал мониторов в директории dir
+func OpenFileMonitorStorage(dir string) (*FileMonitorStorage, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию данных %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, monitorLogFileName)
+
+	// в журнале ссылки вместе с учётными данными, поэтому читать его может только владелец
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал %s: %w", path, err)
+	}
+
+	fs := &FileMonitorStorage{
+		mem:  NewMonitorStorage(),
+		path: path,
+		file: file,
+	}
+
+	fs.records, err = fs.replay()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось восстановить журнал %s: %w", path, err)
+	}
+
+	fs.compactIfNeeded()
+
+	if err := syncDir(dir); err != nil {
+		fs.file.Close()
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// replay читает журнал с начала и наполняет индекс; возвращает число записей в журнале
+func (fs *FileMonitorStorage) replay() (int, error) {
+
+	return replayLog(fs.file, func(line []byte) bool {
+		var rec monitorRecord
+		if err := json.Unmarshal(line, &rec); err != nil || rec.ID <= 0 {
+			return false
+		}
+
+		switch {
+		case rec.Deleted:
+			fs.mem.DeleteMonitor(rec.ID)
+		case rec.Reserved:
+			fs.mem.reserve(rec.ID)
+		case rec.Monitor != nil:
+			fs.mem.restore(*rec.Monitor)
+		case rec.Run != nil:
+			// прогон удалённого монитора пропускаем
+			fs.mem.AppendRun(rec.ID, *rec.Run)
+		}
+
+		return true
+	})
+}
+
+// compactIfNeeded переписывает журнал, когда в нём заметно больше записей, чем нужно хранить:
+// копятся прогоны, вытесненные из истории, и удалённые мониторы. Вызывается под fs.mu
+// (или до того, как хранилище стало доступно)
+func (fs *FileMonitorStorage) compactIfNeeded() {
+
+	if !needsCompaction(fs.records, fs.mem.records()) {
+		return
+	}
+	if err := fs.compact(); err != nil {
+		fmt.Printf("%v - журнал остаётся прежним\n", err)
+	}
+}
+
+// compact переписывает журнал мониторами с их историей; последний выданный номер
+// сохраняется отметкой резерва, чтобы номера удалённых мониторов не выдавались повторно
+func (fs *FileMonitorStorage) compact() error {
+
+	fs.mem.mu.RLock()
+	ids := slices.Sorted(maps.Keys(fs.mem.monitors))
+	var records []any
+	for _, id := range ids {
+		m := fs.mem.monitors[id]
+		records = append(records, monitorRecord{ID: id, Monitor: &m})
+		for _, run := range fs.mem.runs[id] {
+			records = append(records, monitorRecord{ID: id, Run: &run})
+		}
+	}
+	if last := fs.mem.nextID - 1; last > 0 {
+		records = append(records, monitorRecord{ID: last, Reserved: true})
+	}
+	fs.mem.mu.RUnlock()
+
+	file, err := rewriteLog(fs.path, fs.file, records)
+	if err != nil {
+		return err
+	}
+	fs.file = file
+	fs.records = len(records)
+
+	return nil
+}
+
+// SaveMonitor дописывает новый монитор в журнал
+func (fs *FileMonitorStorage) SaveMonitor(m Monitor) (Monitor, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.mem.mu.RLock()
+	m.ID = fs.mem.nextID
+	fs.mem.mu.RUnlock()
+
+	if err := fs.append(monitorRecord{ID: m.ID, Monitor: &m}); err != nil {
+		return Monitor{}, err
+	}
+
+	fs.mem.restore(m)
+
+	return m, nil
+}
+
+// GetMonitor возвращает монитор по номеру
+func (fs *FileMonitorStorage) GetMonitor(id int) (Monitor, bool) {
+
+	return fs.mem.GetMonitor(id)
+}
+
+// ListMonitors возвращает все мониторы по возрастанию номеров
+func (fs *FileMonitorStorage) ListMonitors() []Monitor {
+
+	return fs.mem.ListMonitors()
+}
+
+// DeleteMonitor дописывает в журнал отметку об удалении монитора
+func (fs *FileMonitorStorage) DeleteMonitor(id int) (bool, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.mem.GetMonitor(id); !exists {
+		return false, nil
+	}
+
+	if err := fs.append(monitorRecord{ID: id, Deleted: true}); err != nil {
+		return false, err
+	}
+
+	deleted, err := fs.mem.DeleteMonitor(id)
+	fs.compactIfNeeded()
+
+	return deleted, err
+}
+
+// AppendRun дописывает прогон в журнал и историю монитора и возвращает вытесненные из истории прогоны
+func (fs *FileMonitorStorage) AppendRun(id int, run MonitorRun) ([]MonitorRun, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.mem.GetMonitor(id); !exists {
+		return nil, fmt.Errorf("монитора %d нет", id)
+	}
+
+	if err := fs.append(monitorRecord{ID: id, Run: &run}); err != nil {
+		return nil, err
+	}
+
+	dropped, err := fs.mem.AppendRun(id, run)
+	fs.compactIfNeeded()
+
+	return dropped, err
+}
+
+// Runs возвращает последние limit прогонов (0 - все), новые первыми
+func (fs *FileMonitorStorage) Runs(id int, limit int) ([]MonitorRun, bool) {
+
+	return fs.mem.Runs(id, limit)
+}
+
+// Close закрывает журнал
+func (fs *FileMonitorStorage) Close() error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.file.Close()
+}
+
+// append записывает одну строку журнала и сбрасывает её на диск
+func (fs *FileMonitorStorage) append(rec monitorRecord) error {
+
+	if err := appendLog(fs.file, rec); err != nil {
+		return err
+	}
+	fs.records++
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// MonitorStorage хранилище мониторов в памяти
+type MonitorStorage struct {
+	monitors map[int]Monitor
+	runs     map[int][]MonitorRun // map [номер монитора] прогоны от старых к новым
+	nextID   int
+	mu       sync.RWMutex
+}
+
+// NewMonitorStorage создаёт пустое хранилище мониторов
+func NewMonitorStorage() *MonitorStorage {
+
+	return &MonitorStorage{
+		monitors: make(map[int]Monitor),
+		runs:     make(map[int][]MonitorRun),
+		nextID:   1,
+	}
+}
+
+// SaveMonitor сохраняет новый монитор под следующим номером
+func (s *MonitorStorage) SaveMonitor(m Monitor) (Monitor, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.ID = s.nextID
+	s.monitors[m.ID] = m
+	s.nextID++
+
+	return m, nil
+}
+
+// GetMonitor возвращает монитор по номеру
+func (s *MonitorStorage) GetMonitor(id int) (Monitor, bool) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, exists := s.monitors[id]
+
+	return m, exists
+}
+
+// ListMonitors возвращает все мониторы по возрастанию номеров
+func (s *MonitorStorage) ListMonitors() []Monitor {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	monitors := make([]Monitor, 0, len(s.monitors))
+	for _, m := range s.monitors {
+		monitors = append(monitors, m)
+	}
+	slices.SortFunc(monitors, func(a, b Monitor) int { return a.ID - b.ID })
+
+	return monitors
+}
+
+// DeleteMonitor удаляет монитор вместе с историей
+func (s *MonitorStorage) DeleteMonitor(id int) (bool, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.monitors[id]
+	delete(s.monitors, id)
+	delete(s.runs, id)
+
+	return exists, nil
+}
+
+// AppendRun дописывает прогон в историю, самые старые прогоны сверх MonitorHistory
+// отбрасываются и возвращаются, чтобы можно было удалить их наборы
+func (s *MonitorStorage) AppendRun(id int, run MonitorRun) ([]MonitorRun, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.monitors[id]; !exists {
+		return nil, fmt.Errorf("монитора %d нет", id)
+	}
+
+	var dropped []MonitorRun
+	runs := append(s.runs[id], run)
+	if len(runs) > MonitorHistory {
+		dropped = slices.Clone(runs[:len(runs)-MonitorHistory])
+		runs = slices.Clone(runs[len(runs)-MonitorHistory:])
+	}
+	s.runs[id] = runs
+
+	return dropped, nil
+}
+
+// Runs возвращает последние limit прогонов (0 - все), новые первыми
+func (s *MonitorStorage) Runs(id int, limit int) ([]MonitorRun, bool) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.monitors[id]; !exists {
+		return nil, false
+	}
+
+	runs := s.runs[id]
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	runs = slices.Clone(runs)
+	slices.Reverse(runs)
+
+	return runs, true
+}
+
+// records сколько записей нужно, чтобы сохранить мониторы с историей
+func (s *MonitorStorage) records() int {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.monitors)
+	for _, runs := range s.runs {
+		n += len(runs)
+	}
+
+	return n
+}
+
+// Close для хранилища в памяти ничего не делает
+func (s *MonitorStorage) Close() error {
+
+	return nil
+}
+
+// reserve сдвигает счётчик за номер id (используется при восстановлении)
+func (s *MonitorStorage) reserve(id int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id >= s.nextID {
+		s.nextID = id + 1
+	}
+}
+
+// restore кладёт монитор под его номером и сдвигает счётчик (используется при восстановлении)
+func (s *MonitorStorage) restore(m Monitor) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.monitors[m.ID] = m
+	if m.ID >= s.nextID {
+		s.nextID = m.ID + 1
+	}
+}
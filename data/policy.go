@@ -0,0 +1,270 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Link ссылка на проверку с индивидуальными правилами.
+// В JSON принимается как строка-адрес или как объект {"url": ..., "policy": {...}, "assertions": [...]}
+type Link struct {
+	Url        string      `json:"url"`                  // адрес
+	Policy     *Policy     `json:"policy,omitempty"`     // правила проверки этой ссылки
+	Assertions []Assertion `json:"assertions,omitempty"` // проверки содержимого ответа
+	Send       string      `json:"send,omitempty"`       // что отправить после подключения (tcp, ws)
+	Expect     string      `json:"expect,omitempty"`     // выражение, которому должен соответствовать ответ (tcp, ws)
+
+	ExpectRecords []string `json:"expect_records,omitempty"` // значения, которые должны быть среди DNS записей (dns)
+
+	Proxy         string `json:"proxy,omitempty"`          // через какой прокси проверять ("direct" - напрямую)
+	CompareDirect bool   `json:"compare_direct,omitempty"` // проверить ещё и напрямую, чтобы сравнить
+
+	Method  string            `json:"method,omitempty"`  // HTTP метод вместо HEAD/GET по умолчанию
+	Headers map[string]string `json:"headers,omitempty"` // заголовки запроса (Host задаёт имя хоста в запросе)
+	Body    string            `json:"body,omitempty"`    // тело запроса
+	Auth    *Credentials      `json:"auth,omitempty"`    // учётные данные запроса
+
+	RetryUnsafe bool `json:"retry_unsafe,omitempty"` // повторять при неудаче и методы, меняющие данные (POST, PUT, DELETE...)
+
+	TLSProfile string `json:"tls_profile,omitempty"` // имя профиля TLS из настроек сервера
+}
+
+// UnmarshalJSON принимает ссылку строкой или объектом
+func (l *Link) UnmarshalJSON(b []byte) error {
+
+	var url string
+	if err := json.Unmarshal(b, &url); err == nil {
+		*l = Link{Url: url}
+		return nil
+	}
+
+	// отдельный тип, чтобы не зациклиться на этом же методе
+	type plain Link
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*l = Link(p)
+
+	return nil
+}
+
+// Validate проверяет условия ссылки на содержимое и ответ
+func (l Link) Validate() error {
+
+	for _, a := range l.Assertions {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if l.Expect != "" {
+		if _, err := regexp.Compile(l.Expect); err != nil {
+			return fmt.Errorf("некорректное выражение expect %q: %w", l.Expect, err)
+		}
+	}
+
+	if _, err := ParseProxy(l.Proxy); err != nil {
+		return err
+	}
+
+	if l.Method != "" && !isToken(l.Method) {
+		return fmt.Errorf("некорректный метод %q", l.Method)
+	}
+	for name, value := range l.Headers {
+		if !isToken(name) || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("некорректный заголовок %q", name)
+		}
+	}
+	if l.Auth != nil && l.Auth.Token != "" && (l.Auth.Username != "" || l.Auth.Password != "") {
+		return fmt.Errorf("в auth задаётся либо username и password, либо token")
+	}
+
+	return nil
+}
+
+// isToken проверяет имя метода или заголовка: непустое, из латиницы, цифр и !#$%&'*+-.^_`|~
+func isToken(s string) bool {
+
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ProxyDirect значение proxy, отключающее прокси для ссылки
+const ProxyDirect = "direct"
+
+// ParseProxy разбирает адрес прокси вида http://[user:pass@]host:port или socks5://...;
+// пустая строка и "direct" дают nil
+func ParseProxy(raw string) (*neturl.URL, error) {
+
+	if raw == "" || raw == ProxyDirect {
+		return nil, nil
+	}
+
+	u, err := neturl.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("некорректный адрес прокси %q", raw)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("неподдерживаемый прокси %q: ожидается http, https или socks5", u.Redacted())
+	}
+
+	return u, nil
+}
+
+// Policy правила проверки: пустые поля означают значения по умолчанию
+type Policy struct {
+	Timeout         Duration    `json:"timeout,omitempty"`          // время на проверку, например "10s"
+	ExpectedStatus  StatusCodes `json:"expected_status,omitempty"`  // допустимые коды ответа (по умолчанию 2xx и 3xx)
+	FollowRedirects *bool       `json:"follow_redirects,omitempty"` // следовать ли перенаправлениям (по умолчанию да)
+	MaxRedirects    int         `json:"max_redirects,omitempty"`    // сколько перенаправлений допустимо (по умолчанию 10)
+}
+
+// Merge накладывает на правила заданные поля override и возвращает результат
+func (p Policy) Merge(override *Policy) Policy {
+
+	if override == nil {
+		return p
+	}
+
+	if override.Timeout != 0 {
+		p.Timeout = override.Timeout
+	}
+	if len(override.ExpectedStatus) != 0 {
+		p.ExpectedStatus = override.ExpectedStatus
+	}
+	if override.FollowRedirects != nil {
+		p.FollowRedirects = override.FollowRedirects
+	}
+	if override.MaxRedirects != 0 {
+		p.MaxRedirects = override.MaxRedirects
+	}
+
+	return p
+}
+
+// IsZero сообщает, что правила не отличаются от значений по умолчанию
+func (p Policy) IsZero() bool {
+
+	return p.Timeout == 0 && len(p.ExpectedStatus) == 0 && p.FollowRedirects == nil && p.MaxRedirects == 0
+}
+
+// Duration длительность, в JSON записывается строкой вида "1m30s"
+type Duration time.Duration
+
+// MarshalJSON записывает длительность строкой
+func (d Duration) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON принимает строку вида "10s" или число миллисекунд
+func (d *Duration) UnmarshalJSON(b []byte) error {
+
+	var ms int64
+	if err := json.Unmarshal(b, &ms); err == nil {
+		*d = Duration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("длительность должна быть строкой или числом миллисекунд: %s", b)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("некорректная длительность %q", s)
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// StatusCodes допустимые коды ответа: точные ("200" или 200), классы ("2xx") и диапазоны ("200-299")
+type StatusCodes []string
+
+// UnmarshalJSON принимает коды числами и строками и проверяет их запись
+func (s *StatusCodes) UnmarshalJSON(b []byte) error {
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("expected_status должен быть массивом: %w", err)
+	}
+
+	codes := make(StatusCodes, 0, len(raw))
+	for _, r := range raw {
+		pattern := strings.Trim(string(r), `"`)
+		if _, _, err := statusRange(pattern); err != nil {
+			return err
+		}
+		codes = append(codes, strings.ToLower(pattern))
+	}
+	*s = codes
+
+	return nil
+}
+
+// Match проверяет код ответа; пустой набор означает 2xx и 3xx
+func (s StatusCodes) Match(code int) bool {
+
+	if len(s) == 0 {
+		return code >= 200 && code < 400
+	}
+
+	for _, pattern := range s {
+		lo, hi, err := statusRange(pattern)
+		if err == nil && code >= lo && code <= hi {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statusRange переводит запись кода в диапазон [lo, hi]
+func statusRange(pattern string) (int, int, error) {
+
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	// класс вида 2xx
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") && pattern[0] >= '1' && pattern[0] <= '5' {
+		lo := int(pattern[0]-'0') * 100
+		return lo, lo + 99, nil
+	}
+
+	// диапазон вида 200-299
+	if from, to, ok := strings.Cut(pattern, "-"); ok {
+		lo, err1 := strconv.Atoi(from)
+		hi, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || lo < 100 || hi > 599 || lo > hi {
+			return 0, 0, fmt.Errorf("некорректный диапазон кодов %q", pattern)
+		}
+		return lo, hi, nil
+	}
+
+	// точный код
+	code, err := strconv.Atoi(pattern)
+	if err != nil || code < 100 || code > 599 {
+		return 0, 0, fmt.Errorf("некорректный код ответа %q", pattern)
+	}
+
+	return code, code, nil
+}
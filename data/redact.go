@@ -0,0 +1,179 @@
+package data
+
+import (
+	neturl "net/url"
+	"strings"
+)
+
+// Redacted чем заменяются секреты в результатах (так же, как пароль в url.URL.Redacted)
+const Redacted = "xxxxx"
+
+// minSecret секреты короче не вычищаются из текста: иначе пострадает всё подряд
+const minSecret = 4
+
+// sensitiveWords части имён заголовков и параметров запроса, значения которых считаются секретами
+var sensitiveWords = []string{"auth", "token", "secret", "password", "passwd", "key", "cookie", "session", "signature", "credential"}
+
+// Credentials учётные данные запроса: basic (username, password) или bearer (token)
+type Credentials struct {
+	Username string `json:"username,omitempty"` // имя для basic авторизации
+	Password string `json:"password,omitempty"` // пароль для basic авторизации
+	Token    string `json:"token,omitempty"`    // токен для заголовка Authorization: Bearer
+}
+
+// Sensitive сообщает, что значение заголовка или параметра с таким именем - секрет
+func Sensitive(name string) bool {
+
+	name = strings.ToLower(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactURL заменяет в адресе пароль и значения секретных параметров запроса;
+// адрес без секретов возвращается как есть
+func RedactURL(raw string) string {
+
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = neturl.UserPassword(u.User.Username(), Redacted)
+		changed = true
+	}
+
+	query := u.Query()
+	for name, values := range query {
+		if !Sensitive(name) {
+			continue
+		}
+		for i := range values {
+			values[i] = Redacted
+		}
+		changed = true
+	}
+	if !changed {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Secrets значения, которые нельзя сохранять и показывать: пароли и токены ссылки,
+// секретные заголовки и параметры запроса, пароль прокси
+func (l Link) Secrets() []string {
+
+	var secrets []string
+
+	if l.Auth != nil {
+		secrets = append(secrets, l.Auth.Password, l.Auth.Token)
+	}
+	for name, value := range l.Headers {
+		if Sensitive(name) {
+			secrets = append(secrets, value)
+			// значение вида "Bearer abc" встречается и без схемы
+			if _, token, ok := strings.Cut(value, " "); ok {
+				secrets = append(secrets, token)
+			}
+		}
+	}
+
+	for _, raw := range []string{l.Url, l.Proxy} {
+		u, err := neturl.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+		for name, values := range u.Query() {
+			if Sensitive(name) {
+				secrets = append(secrets, values...)
+			}
+		}
+	}
+
+	// пустые и слишком короткие значения не ищем
+	kept := secrets[:0]
+	for _, s := range secrets {
+		if len(s) >= minSecret {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
+
+// Redacted копия ссылки для выдачи: пароли, токены и секретные заголовки заменены
+// на Redacted, тело запроса убрано
+func (l Link) Redacted() Link {
+
+	l.Url = RedactURL(l.Url)
+	l.Proxy = RedactURL(l.Proxy)
+	l.Body = ""
+
+	if l.Auth != nil {
+		auth := *l.Auth
+		if auth.Password != "" {
+			auth.Password = Redacted
+		}
+		if auth.Token != "" {
+			auth.Token = Redacted
+		}
+		l.Auth = &auth
+	}
+
+	if l.Headers != nil {
+		headers := make(map[string]string, len(l.Headers))
+		for name, value := range l.Headers {
+			if Sensitive(name) {
+				value = Redacted
+			}
+			headers[name] = value
+		}
+		l.Headers = headers
+	}
+
+	return l
+}
+
+// Redact убирает секреты из адресов и текстов результата
+func (r *CheckResult) Redact(secrets []string) {
+
+	scrub := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+		return s
+	}
+	redactURL := func(s string) string {
+		return scrub(RedactURL(s))
+	}
+
+	r.Url = redactURL(r.Url)
+	r.FinalURL = redactURL(r.FinalURL)
+	r.Error = scrub(r.Error)
+	r.Warning = scrub(r.Warning)
+	r.Banner = scrub(r.Banner)
+	r.Proxy = redactURL(r.Proxy)
+
+	for i := range r.Redirects {
+		r.Redirects[i].URL = redactURL(r.Redirects[i].URL)
+		r.Redirects[i].Location = redactURL(r.Redirects[i].Location)
+	}
+	for i := range r.Assertions {
+		r.Assertions[i].Reason = scrub(r.Assertions[i].Reason)
+	}
+
+	if r.Direct != nil {
+		r.Direct.Redact(secrets)
+	}
+}
@@ -0,0 +1,98 @@
+package data
+
+import "time"
+
+// виды ошибок проверки ссылки
+const (
+	ErrorKindDNS         = "dns"         // не удалось разрешить имя хоста
+	ErrorKindRefused     = "refused"     // в соединении отказано
+	ErrorKindTLS         = "tls"         // ошибка TLS рукопожатия или сертификата
+	ErrorKindTimeout     = "timeout"     // истекло время ожидания
+	ErrorKindStatus      = "status"      // ресурс ответил недопустимым кодом
+	ErrorKindContent     = "content"     // содержимое ответа не прошло проверки
+	ErrorKindUnsupported = "unsupported" // для схемы ссылки нет проверки
+	ErrorKindInvalid     = "invalid"     // ссылка записана некорректно
+	ErrorKindBlocked     = "blocked"     // адрес запрещён правилами исходящих соединений
+	ErrorKindProxy       = "proxy"       // не удалось пройти через прокси
+	ErrorKindOther       = "other"       // прочие ошибки
+)
+
+// CheckResult описывает подробный результат проверки одной ссылки
+type CheckResult struct {
+	Url        string    `json:"url"`                   // адрес из запроса
+	Origins    []string  `json:"origins,omitempty"`     // как адрес был записан в запросе, если иначе, чем url
+	Status     string    `json:"status"`                // статус прежней модели: available или not available
+	State      Status    `json:"state"`                 // состояние ресурса (up, degraded, down, unknown, blocked, invalid)
+	Reasons    []string  `json:"reasons,omitempty"`     // почему ресурс не up: вид ошибки или причины degraded
+	Protocol   string    `json:"protocol,omitempty"`    // схема, по которой шла проверка (http, https, tcp...)
+	Method     string    `json:"method,omitempty"`      // HTTP метод, которым получен ответ
+	StatusCode int       `json:"status_code,omitempty"` // HTTP код ответа
+	LatencyMs  int64     `json:"latency_ms"`            // время проверки в миллисекундах
+	FinalURL   string    `json:"final_url,omitempty"`   // адрес после всех перенаправлений
+	ErrorKind  string    `json:"error_kind,omitempty"`  // вид ошибки (dns, refused, tls, timeout, status, content, unsupported, invalid, blocked, proxy, other)
+	Error      string    `json:"error,omitempty"`       // текст ошибки
+	CheckedAt  time.Time `json:"checked_at"`            // время проверки
+	Timings    *Timings  `json:"timings,omitempty"`     // время по фазам последней попытки
+	Slow       bool      `json:"slow,omitempty"`        // проверка заняла больше порога медленных ссылок
+	Attempts   int       `json:"attempts"`              // сколько попыток понадобилось
+	Flaky      bool      `json:"flaky,omitempty"`       // доступен, но не с первой попытки
+	Warning    string    `json:"warning,omitempty"`     // почему ресурс в статусе degraded
+	Policy     *Policy   `json:"policy,omitempty"`      // правила, по которым шла проверка
+
+	Assertions []AssertionResult `json:"assertions,omitempty"`  // итоги проверок содержимого
+	TLS        *TLSInfo          `json:"tls,omitempty"`         // сведения о сертификатах https ресурса
+	TLSProfile string            `json:"tls_profile,omitempty"` // профиль TLS, с которым шла проверка
+
+	Redirects           []RedirectHop `json:"redirects,omitempty"`             // пройденные перенаправления по порядку
+	CrossDomainRedirect bool          `json:"cross_domain_redirect,omitempty"` // перенаправление увело на другой домен
+	HTTPSDowngrade      bool          `json:"https_downgrade,omitempty"`       // перенаправление с https на http
+
+	Banner string   `json:"banner,omitempty"` // ответ сервиса при проверке порта или по websocket
+	DNS    *DNSInfo `json:"dns,omitempty"`    // итоги разрешения имени
+	Health string   `json:"health,omitempty"` // ответ gRPC health-check (SERVING, NOT_SERVING, UNKNOWN...)
+
+	Proxy  string       `json:"proxy,omitempty"`  // через какой прокси шла проверка (без пароля)
+	Direct *CheckResult `json:"direct,omitempty"` // та же проверка напрямую, если просили сравнить
+}
+
+// Timings время проверки по фазам в миллисекундах (с точностью до десятых)
+type Timings struct {
+	DNSMs     float64 `json:"dns_ms"`     // разрешение имени
+	ConnectMs float64 `json:"connect_ms"` // установка TCP соединения
+	TLSMs     float64 `json:"tls_ms"`     // TLS рукопожатие
+	TTFBMs    float64 `json:"ttfb_ms"`    // от начала проверки до первого байта ответа
+	TotalMs   float64 `json:"total_ms"`   // вся проверка, включая чтение тела
+	Reused    bool    `json:"reused"`     // соединение взято из пула: фаз DNS, TCP и TLS не было
+}
+
+// DNSInfo итоги разрешения имени
+type DNSInfo struct {
+	Type     string   `json:"type"`               // тип записей (A, AAAA, CNAME, MX, TXT)
+	Resolver string   `json:"resolver,omitempty"` // адрес резолвера (пусто - системный)
+	Records  []string `json:"records,omitempty"`  // полученные записи
+}
+
+// RedirectHop одно звено цепочки перенаправлений
+type RedirectHop struct {
+	URL        string `json:"url"`         // адрес звена
+	StatusCode int    `json:"status_code"` // код ответа с перенаправлением
+	Location   string `json:"location"`    // куда перенаправил
+}
+
+// TLSInfo сведения о сертификатах ресурса
+type TLSInfo struct {
+	Version          string     `json:"version,omitempty"` // версия TLS
+	Chain            []CertInfo `json:"chain"`             // цепочка начиная с сертификата ресурса
+	DaysLeft         int        `json:"days_left"`         // сколько дней до истечения ближайшего сертификата цепочки
+	HostnameMismatch bool       `json:"hostname_mismatch"` // сертификат выдан не на этот хост
+	SelfSigned       bool       `json:"self_signed"`       // сертификат самоподписанный
+}
+
+// CertInfo сведения об одном сертификате
+type CertInfo struct {
+	Subject  string    `json:"subject"`        // владелец
+	Issuer   string    `json:"issuer"`         // издатель
+	SANs     []string  `json:"sans,omitempty"` // альтернативные имена (DNS и IP)
+	NotAfter time.Time `json:"not_after"`      // действителен до
+	DaysLeft int       `json:"days_left"`      // сколько дней до истечения
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Status состояние ресурса по итогам проверки
+type Status string
+
+// состояния ресурса; набор может расширяться, старым клиентам
+// любое состояние отдаётся одной из двух строк (см. Legacy)
+const (
+	UpStatus       Status = "up"       // доступен
+	DegradedStatus Status = "degraded" // доступен, но есть повод для беспокойства (см. Reasons)
+	DownStatus     Status = "down"     // недоступен
+	UnknownStatus  Status = "unknown"  // доступность не удалось выяснить (нет проверки для схемы, отказал прокси)
+	BlockedStatus  Status = "blocked"  // проверка запрещена правилами исходящих соединений
+	InvalidStatus  Status = "invalid"  // ссылка записана некорректно
+)
+
+// Statuses все состояния от лучшего к худшему
+var Statuses = []Status{UpStatus, DegradedStatus, DownStatus, UnknownStatus, BlockedStatus, InvalidStatus}
+
+// статусы прежней модели, которые понимают старые клиенты
+const (
+	AvailableStatus    = "available"
+	NotAvailableStatus = "not available"
+)
+
+// причины состояния degraded; у остальных состояний причина - вид ошибки (ErrorKind)
+const (
+	ReasonSlow          = "slow"           // проверка заняла больше порога медленных ссылок
+	ReasonFlaky         = "flaky"          // ресурс ответил не с первой попытки
+	ReasonCertExpiring  = "cert_expiring"  // сертификат скоро истекает
+	ReasonHealthUnknown = "health_unknown" // gRPC сервис не знает своего состояния
+)
+
+// Available сообщает, что ресурс отвечает (up или degraded)
+func (s Status) Available() bool {
+
+	return s == UpStatus || s == DegradedStatus
+}
+
+// Legacy статус прежней модели: available или not available
+func (s Status) Legacy() string {
+
+	if s.Available() {
+		return AvailableStatus
+	}
+
+	return NotAvailableStatus
+}
+
+// ParseStatus переводит статус в состояние; статусы прежней модели
+// (available, not available) понимаются, неизвестные значения дают unknown
+func ParseStatus(s string) Status {
+
+	switch {
+	case slices.Contains(Statuses, Status(s)):
+		return Status(s)
+	case s == AvailableStatus:
+		return UpStatus
+	case s == NotAvailableStatus:
+		return DownStatus
+	}
+
+	return UnknownStatus
+}
+
+// UnmarshalJSON читает результат; у результатов, сохранённых до появления
+// состояний, оно восстанавливается из прежнего статуса
+func (r *CheckResult) UnmarshalJSON(b []byte) error {
+
+	// отдельный тип, чтобы не зациклиться на этом же методе
+	type plain CheckResult
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*r = CheckResult(p)
+	r.fillState()
+
+	return nil
+}
+
+// fillState восстанавливает отсутствующее состояние из прежнего статуса
+// и приводит прежний статус к одной из двух строк
+func (r *CheckResult) fillState() {
+
+	if r.State == "" {
+		r.State = ParseStatus(r.Status)
+	}
+	r.Status = r.State.Legacy()
+}
+
+// Settle выводит состояние и причины из итогов проверки и заполняет прежний статус:
+// ошибка задаёт состояние по своему виду, медленный ответ и успех не с первой
+// попытки делают доступный ресурс degraded
+func (r *CheckResult) Settle() {
+
+	// результат без состояния (например, от сторонней проверки) судим по статусу
+	r.fillState()
+
+	switch r.ErrorKind {
+	case "":
+		if r.State.Available() && r.Slow {
+			r.Degrade(ReasonSlow)
+		}
+		if r.State.Available() && r.Flaky {
+			r.Degrade(ReasonFlaky)
+		}
+
+	case ErrorKindBlocked:
+		r.State = BlockedStatus
+		r.Reasons = []string{r.ErrorKind}
+
+	case ErrorKindInvalid:
+		r.State = InvalidStatus
+		r.Reasons = []string{r.ErrorKind}
+
+	case ErrorKindUnsupported, ErrorKindProxy:
+		r.State = UnknownStatus
+		r.Reasons = []string{r.ErrorKind}
+
+	default:
+		r.State = DownStatus
+		r.Reasons = []string{r.ErrorKind}
+	}
+
+	r.Status = r.State.Legacy()
+}
+
+// Degrade переводит результат в degraded с причиной
+func (r *CheckResult) Degrade(reason string) {
+
+	r.State = DegradedStatus
+	for _, have := range r.Reasons {
+		if have == reason {
+			return
+		}
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
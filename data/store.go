@@ -0,0 +1,31 @@
+package data
+
+// Store описывает хранилище результатов проверок
+type Store interface {
+	SaveResults(results map[string]CheckResult) (int, error) // сохраняет набор и возвращает его номер
+	ReserveID() (int, error)                                 // резервирует номер под набор, который сохранится позже
+	PutResults(id int, results map[string]CheckResult) error // сохраняет набор под зарезервированным номером
+	GetResults(id int) (map[string]CheckResult, bool)        // возвращает набор по номеру
+	ListResults() []int                                      // возвращает номера всех наборов по возрастанию
+	DeleteResults(id int) (bool, error)                      // удаляет набор, false - если его не было
+	Stats() Stats                                            // сводка по хранилищу
+	Close() error                                            // освобождает ресурсы хранилища
+}
+
+// Stats сводка по хранилищу
+type Stats struct {
+	Sets   int            `json:"sets"`    // количество наборов
+	Links  int            `json:"links"`   // количество результатов во всех наборах
+	States map[Status]int `json:"states"`  // map [состояние] сколько результатов в нём
+	NextID int            `json:"next_id"` // номер, который получит следующий набор
+}
+
+// Open открывает хранилище: при непустом dir - файловое в этой директории, иначе в памяти
+func Open(dir string) (Store, error) {
+
+	if dir == "" {
+		return NewStorage(), nil
+	}
+
+	return OpenFileStorage(dir)
+}
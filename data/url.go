@@ -0,0 +1,259 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultPorts порты по умолчанию, которые не пишутся в нормализованном адресе
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"grpcs": "443",
+	"dns":   "53",
+}
+
+// NormalizeURL приводит адрес к единому виду, чтобы одна и та же ссылка,
+// записанная по-разному, проверялась один раз:
+//   - без схемы подставляется http://, схема и хост в нижнем регистре;
+//   - национальные домены переводятся в punycode (пример.рф -> xn--e1afmkfd.xn--p1ai);
+//   - порт по умолчанию для схемы и фрагмент (#...) отбрасываются;
+//   - косая черта в корне убирается (http://site.ru/ -> http://site.ru), в остальных путях сохраняется;
+//   - dns ссылка без резолвера (dns:///имя?type=A) остаётся без хоста.
+//
+// Адрес, который не может быть ссылкой, возвращает ошибку с причиной
+func NormalizeURL(raw string) (string, error) {
+
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", errors.New("пустой адрес")
+	}
+	if strings.ContainsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return "", errors.New("адрес содержит пробелы или управляющие символы")
+	}
+
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := neturl.Parse(s)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", fmt.Errorf("некорректный адрес: %v", err)
+	}
+
+	// в dns:///имя резолвер не указан: хоста нет, имя записано в пути
+	if u.Scheme == "dns" && u.Host == "" {
+		if strings.Trim(u.Path, "/") == "" {
+			return "", errors.New("в dns адресе нет имени")
+		}
+		u.Fragment = ""
+		u.RawFragment = ""
+		return u.String(), nil
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("некорректный порт %q", port)
+		}
+		if defaultPorts[u.Scheme] == port {
+			port = ""
+		}
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	// фрагмент на сервер не отправляется
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Path == "/" {
+		u.Path = ""
+		u.RawPath = ""
+	}
+
+	return u.String(), nil
+}
+
+// normalizeHost проверяет имя хоста или IP адрес и переводит имя в нижний регистр и punycode
+func normalizeHost(host string) (string, error) {
+
+	if host == "" {
+		return "", errors.New("в адресе нет хоста")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label == "" {
+			return "", fmt.Errorf("некорректное имя хоста %q", host)
+		}
+
+		if !isASCII(label) {
+			encoded, err := punycode(label)
+			if err != nil {
+				return "", fmt.Errorf("некорректное имя хоста %q: %v", host, err)
+			}
+			label = "xn--" + encoded
+			labels[i] = label
+		}
+
+		if len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf("некорректное имя хоста %q", host)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return "", fmt.Errorf("недопустимый символ %q в имени хоста %q", r, host)
+			}
+		}
+	}
+
+	host = strings.Join(labels, ".")
+	if len(host) > 253 {
+		return "", fmt.Errorf("слишком длинное имя хоста (%d символов)", len(host))
+	}
+
+	return host, nil
+}
+
+// isASCII сообщает, что строка состоит только из ASCII символов
+func isASCII(s string) bool {
+
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// параметры punycode из RFC 3492
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// punycode кодирует метку домена по RFC 3492 (без префикса xn--)
+func punycode(label string) (string, error) {
+
+	runes := []rune(label)
+
+	var out []byte
+	for _, r := range runes {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled := basic; handled < len(runes); {
+		// наименьший ещё не закодированный символ
+		m := rune(unicode.MaxRune)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		delta += int(m-n) * (handled + 1)
+		if delta < 0 {
+			return "", errors.New("переполнение punycode")
+		}
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := min(max(k-bias, punyTMin), punyTMax)
+				if q < t {
+					break
+				}
+				out = append(out, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out = append(out, punyDigit(q))
+
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+
+		delta++
+		n++
+	}
+
+	return string(out), nil
+}
+
+// punyAdapt пересчитывает смещение после очередного символа
+func punyAdapt(delta, points int, first bool) int {
+
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+// punyDigit цифра punycode: a-z для 0-25, 0-9 для 26-35
+func punyDigit(d int) byte {
+
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 
 	"verifi-server/api"
+	"verifi-server/checker"
 	"verifi-server/cli"
+	"verifi-server/data"
 	"verifi-server/server"
 
 	"github.com/joho/godotenv"
@@ -22,16 +24,30 @@ func main() {
 		port = "8080"
 	}
 
-	// запускаем api
-	api.Init()
+	// открываем хранилища результатов и мониторов: файловые, если указана директория данных
+	store, err := data.Open(os.Getenv("VERIFI_DATA_DIR"))
+	if err != nil {
+		fmt.Printf("Ошибка открытия хранилища: %v\n", err)
+		return
+	}
+	monitors, err := data.OpenMonitors(os.Getenv("VERIFI_DATA_DIR"))
+	if err != nil {
+		fmt.Printf("Ошибка открытия хранилища мониторов: %v\n", err)
+		return
+	}
+
+	// запускаем общий движок проверок и api
+	engine := checker.NewEngine(checker.ConfigFromEnv())
+	checker.SetDefaultEngine(engine)
+	handlers := api.Init(store, monitors, engine)
 
 	// запускаем сервер
-	err := server.Run(port)
+	err = server.Run(port)
 	if err != nil {
 		fmt.Printf("Ошибка запуска сервера: %v\n", err)
 		return
 	}
 
 	// запускаем CLI
-	cli.RunCLI(port)
+	cli.RunCLI(port, handlers, store, monitors)
 }
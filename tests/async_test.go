@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"verifi-server/api"
+	"verifi-server/data"
+	"verifi-server/server"
+)
+
+func TestCheckAsync(t *testing.T) {
+	// медленный ресурс держит проверку, пока тест не отпустит его
+	release := make(chan struct{})
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	baseURL := strings.TrimSuffix(mock.URL, "/")
+
+	server.Srv.Mu.Lock()
+	server.Srv.IsShutdown = false
+	server.Srv.Mu.Unlock()
+
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
+	body := `{"links": ["` + baseURL + `/fast", "` + baseURL + `/slow"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check?async=true", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("ожидали статус %d, получили %d", http.StatusAccepted, rec.Code)
+	}
+
+	var started api.ResponseJob
+	if err := json.NewDecoder(rec.Body).Decode(&started); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+	if started.State != api.JobPending || started.Total != 2 || started.LinksNum == 0 {
+		t.Fatalf("неожиданный ответ на запуск: %+v", started)
+	}
+
+	// poll опрашивает ход проверки по номеру
+	poll := func() api.ResponseJob {
+		req := httptest.NewRequest(http.MethodGet, "/api/check/"+strconv.Itoa(started.LinksNum), nil)
+		req.SetPathValue("links_num", strconv.Itoa(started.LinksNum))
+		rec := httptest.NewRecorder()
+		h.CheckGetHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("ожидали статус %d, получили %d", http.StatusOK, rec.Code)
+		}
+
+		var resp api.ResponseJob
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal("не удалось декодировать ответ:", err)
+		}
+		return resp
+	}
+
+	// ждём частичный результат по быстрой ссылке
+	deadline := time.Now().Add(5 * time.Second)
+	resp := poll()
+	for resp.Done < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		resp = poll()
+	}
+	if resp.State != api.JobPending || resp.Done != 1 || resp.Links[baseURL+"/fast"] != api.AvailableStatus {
+		t.Fatalf("ожидали частичный результат, получили %+v", resp)
+	}
+
+	// отпускаем медленную ссылку и ждём завершения
+	close(release)
+	for resp.State == api.JobPending && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		resp = poll()
+	}
+	if resp.State != api.JobDone || resp.Done != 2 || len(resp.Results) != 2 {
+		t.Fatalf("ожидали завершённую проверку, получили %+v", resp)
+	}
+}
+
+func TestCheckGetHandlerNotFound(t *testing.T) {
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
+	for _, num := range []string{"42", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/check/"+num, nil)
+		req.SetPathValue("links_num", num)
+		rec := httptest.NewRecorder()
+		h.CheckGetHandler(rec, req)
+
+		if rec.Code != http.StatusNotFound && rec.Code != http.StatusBadRequest {
+			t.Errorf("для номера %s ожидали 404 или 400, получили %d", num, rec.Code)
+		}
+	}
+}
@@ -9,9 +9,28 @@ import (
 	"testing"
 
 	"verifi-server/api"
+	"verifi-server/checker"
+	"verifi-server/data"
 	"verifi-server/server"
 )
 
+// общий движок проверок для тестов
+var testEngine = checker.NewEngine(testConfig())
+
+// разовые проверки api.CheckLink идут тем же движком
+func init() {
+	checker.SetDefaultEngine(testEngine)
+}
+
+// testConfig настройки по умолчанию с разрешёнными обращениями к loopback,
+// на котором работают тестовые серверы
+func testConfig() checker.Config {
+	cfg := checker.DefaultConfig()
+	cfg.EgressAllow = []string{"127.0.0.0/8", "::1"}
+
+	return cfg
+}
+
 // Mock-сервер для имитации внешних URL
 func startMockServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -68,6 +87,9 @@ func TestCheckPostHandler(t *testing.T) {
 		},
 	}
 
+	// у каждого теста своё хранилище
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Имитируем состояние сервера
@@ -86,7 +108,7 @@ func TestCheckPostHandler(t *testing.T) {
 			rec := httptest.NewRecorder()
 
 			// Вызываем обработчик
-			api.CheckPostHandler(rec, req)
+			h.CheckPostHandler(rec, req)
 
 			// Проверяем статус
 			if rec.Code != tt.expectedStatus {
@@ -139,3 +161,57 @@ func TestIsAvailable(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPostHandlerDetailed(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	baseURL := strings.TrimSuffix(mock.URL, "/")
+
+	server.Srv.Mu.Lock()
+	server.Srv.IsShutdown = false
+	server.Srv.Mu.Unlock()
+
+	body := `{"links": ["` + baseURL + `/ok", "` + baseURL + `/bad"], "detailed": true}`
+	req := httptest.NewRequest(http.MethodPost, "/check", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+	h.CheckPostHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ожидали статус %d, получили %d", http.StatusOK, rec.Code)
+	}
+
+	var resp api.ResponseLinks
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+
+	// старая форма ответа сохраняется
+	if resp.Links[baseURL+"/ok"] != api.AvailableStatus {
+		t.Errorf("ожидали %s в links, получили %s", api.AvailableStatus, resp.Links[baseURL+"/ok"])
+	}
+
+	ok := resp.Results[baseURL+"/ok"]
+	if ok.StatusCode != http.StatusOK || ok.Status != api.AvailableStatus {
+		t.Errorf("неожиданный результат для /ok: %+v", ok)
+	}
+
+	bad := resp.Results[baseURL+"/bad"]
+	if bad.StatusCode != http.StatusNotFound || bad.ErrorKind != "status" {
+		t.Errorf("неожиданный результат для /bad: %+v", bad)
+	}
+}
+
+func TestCheckLinkErrorKind(t *testing.T) {
+	// закрытый сервер даёт отказ в соединении
+	mock := startMockServer()
+	addr := mock.URL
+	mock.Close()
+
+	res := api.CheckLink(addr + "/ok")
+	if res.ErrorKind != "refused" {
+		t.Errorf("ожидали вид ошибки refused, получили %q (%s)", res.ErrorKind, res.Error)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestCheckAssertions(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Write([]byte("<html><div class=banner>Service error</div></html>"))
+		case "/api":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status": "ok", "items": [{"id": 7, "ready": true}]}`))
+		}
+	}))
+	defer mock.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		assertions []data.Assertion
+		available  bool
+	}{
+		{
+			name:       "строка найдена",
+			path:       "/page",
+			assertions: []data.Assertion{{Contains: "<html>"}},
+			available:  true,
+		},
+		{
+			name:       "баннер ошибки на странице с 200",
+			path:       "/page",
+			assertions: []data.Assertion{{Contains: "<html>"}, {NotContains: "Service error"}},
+			available:  false,
+		},
+		{
+			name:       "регулярное выражение",
+			path:       "/page",
+			assertions: []data.Assertion{{Regex: `class=\w+`}},
+			available:  true,
+		},
+		{
+			name:       "поле JSON совпадает",
+			path:       "/api",
+			assertions: []data.Assertion{{JSONPath: "$.items[0].id", Equals: []byte("7")}},
+			available:  true,
+		},
+		{
+			name:       "поле JSON не совпадает",
+			path:       "/api",
+			assertions: []data.Assertion{{JSONPath: "status", Equals: []byte(`"degraded"`)}},
+			available:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(data.Link{Url: mock.URL + tt.path, Assertions: tt.assertions})
+
+			if (res.Status == data.AvailableStatus) != tt.available {
+				t.Fatalf("ожидали доступность %v, получили %+v", tt.available, res)
+			}
+			if len(res.Assertions) != len(tt.assertions) {
+				t.Fatalf("ожидали %d итогов проверок, получили %d", len(tt.assertions), len(res.Assertions))
+			}
+			if !tt.available && (res.ErrorKind != data.ErrorKindContent || res.Error == "") {
+				t.Errorf("ожидали причину невыполнения проверки, получили %+v", res)
+			}
+		})
+	}
+}
+
+func TestCheckAssertionsTruncatedBody(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "ok", "padding": "` + strings.Repeat("x", 100) + `"}`))
+	}))
+	defer mock.Close()
+
+	tests := []struct {
+		name       string
+		maxBody    int64
+		assertions []data.Assertion
+		reason     string
+	}{
+		{
+			name:       "тело не читается",
+			maxBody:    0,
+			assertions: []data.Assertion{{Contains: "ok"}},
+			reason:     "не прочитано",
+		},
+		{
+			name:       "JSON обрезан",
+			maxBody:    32,
+			assertions: []data.Assertion{{JSONPath: "status", Equals: []byte(`"ok"`)}},
+			reason:     "обрезано",
+		},
+		{
+			name:       "строки нет в прочитанной части",
+			maxBody:    32,
+			assertions: []data.Assertion{{Contains: `"}`}},
+			reason:     "обрезано",
+		},
+		{
+			name:       "запретная строка может быть за прочитанной частью",
+			maxBody:    32,
+			assertions: []data.Assertion{{NotContains: "FATAL ERROR"}},
+			reason:     "обрезано",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MaxBodyBytes = tt.maxBody
+			engine := checker.NewEngine(cfg)
+			defer engine.Close()
+
+			res := engine.CheckLink(data.Link{Url: mock.URL, Assertions: tt.assertions})
+
+			if res.ErrorKind != data.ErrorKindContent || !strings.Contains(res.Error, tt.reason) {
+				t.Errorf("ожидали ошибку содержимого с причиной %q, получили %+v", tt.reason, res)
+			}
+		})
+	}
+
+	// строка в прочитанной части находится и в обрезанном теле
+	cfg := testConfig()
+	cfg.MaxBodyBytes = 32
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	if res := engine.CheckLink(data.Link{Url: mock.URL, Assertions: []data.Assertion{{Contains: `"ok"`}}}); res.State != data.UpStatus {
+		t.Errorf("ожидали up, получили %+v", res)
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+// startDNSServer локальный резолвер-заглушка: svc.test. -> A 10.0.0.7 и TXT "v=ok", остальное - NXDOMAIN
+func startDNSServer(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := dnsAnswer(buf[:n]); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	return pc
+}
+
+// dnsAnswer собирает ответ на запрос с одним вопросом
+func dnsAnswer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// читаем имя из вопроса
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		l := int(query[i])
+		labels = append(labels, string(query[i+1:i+1+l]))
+		i += 1 + l
+	}
+	qtype := binary.BigEndian.Uint16(query[i+1:])
+	question := query[12 : i+5]
+	name := strings.ToLower(strings.Join(labels, "."))
+
+	var rdata []byte
+	switch {
+	case name == "svc.test" && qtype == 1: // A
+		rdata = []byte{10, 0, 0, 7}
+	case name == "svc.test" && qtype == 16: // TXT
+		rdata = append([]byte{4}, "v=ok"...)
+	}
+
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	flags := uint16(0x8180)
+	if name != "svc.test" {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	if rdata != nil {
+		binary.BigEndian.PutUint16(resp[6:], 1)
+	}
+	resp = append(resp, question...)
+
+	if rdata != nil {
+		resp = append(resp, 0xC0, 12) // ссылка на имя из вопроса
+		resp = binary.BigEndian.AppendUint16(resp, qtype)
+		resp = binary.BigEndian.AppendUint16(resp, 1)
+		resp = binary.BigEndian.AppendUint32(resp, 60)
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+	}
+
+	return resp
+}
+
+func TestCheckDNS(t *testing.T) {
+	pc := startDNSServer(t)
+	defer pc.Close()
+
+	resolver := pc.LocalAddr().String()
+
+	tests := []struct {
+		name      string
+		link      data.Link
+		available bool
+		kind      string
+	}{
+		{"запись A", data.Link{Url: "dns://" + resolver + "/svc.test"}, true, ""},
+		{"ожидаемый адрес", data.Link{Url: "dns://" + resolver + "/svc.test?type=A", ExpectRecords: []string{"10.0.0.7"}}, true, ""},
+		{"запись TXT", data.Link{Url: "dns://" + resolver + "/svc.test?type=txt", ExpectRecords: []string{"v=ok"}}, true, ""},
+		{"другой адрес", data.Link{Url: "dns://" + resolver + "/svc.test", ExpectRecords: []string{"10.0.0.8"}}, false, data.ErrorKindContent},
+		{"нет такого имени", data.Link{Url: "dns://" + resolver + "/missing.test"}, false, data.ErrorKindDNS},
+		{"неизвестный тип", data.Link{Url: "dns://" + resolver + "/svc.test?type=SRV"}, false, data.ErrorKindInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(tt.link)
+
+			if (res.Status == data.AvailableStatus) != tt.available || res.ErrorKind != tt.kind {
+				t.Errorf("ожидали доступность %v (%q), получили %+v", tt.available, tt.kind, res)
+			}
+		})
+	}
+
+	res := testEngine.CheckLink(data.Link{Url: "dns://" + resolver + "/svc.test"})
+	if res.DNS == nil || res.DNS.Resolver != resolver || len(res.DNS.Records) != 1 || res.DNS.Records[0] != "10.0.0.7" {
+		t.Errorf("неполные итоги разрешения: %+v", res.DNS)
+	}
+}
+
+func TestCheckDNSResolverEgress(t *testing.T) {
+	pc := startDNSServer(t)
+	defer pc.Close()
+
+	resolver := pc.LocalAddr().String()
+
+	// правила по умолчанию: внутренние адреса запрещены
+	cfg := checker.DefaultConfig()
+	cfg.DNSResolver = resolver
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	// настроенному резолверу во внутренней сети доверяем
+	if res := engine.CheckLink(data.Link{Url: "dns:///svc.test"}); res.State != data.UpStatus {
+		t.Errorf("ожидали up через настроенный резолвер, получили %+v", res)
+	}
+
+	// резолвер из ссылки сверяется с правилами, и запрет виден как blocked
+	res := engine.CheckLink(data.Link{Url: "dns://" + resolver + "/svc.test"})
+	if res.State != data.BlockedStatus || res.ErrorKind != data.ErrorKindBlocked {
+		t.Errorf("ожидали blocked для резолвера из ссылки, получили %+v", res)
+	}
+}
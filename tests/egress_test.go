@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"verifi-server/api"
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestEgressPolicy(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			http.Redirect(w, r, "http://10.0.0.1/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	tests := []struct {
+		name   string
+		config func(*checker.Config)
+		url    string
+		status data.Status
+	}{
+		{"loopback по умолчанию запрещён", nil, mock.URL, data.BlockedStatus},
+		{"адрес метаданных облака", nil, "http://169.254.169.254/latest/meta-data/", data.BlockedStatus},
+		{"имя проверяется по разрешённому адресу", nil, "tcp://localhost:6379", data.BlockedStatus},
+		{"разрешённая сеть", func(c *checker.Config) { c.EgressAllow = []string{"127.0.0.0/8"} }, mock.URL, data.UpStatus},
+		{"внутренние адреса разрешены", func(c *checker.Config) { c.EgressAllowPrivate = true }, mock.URL, data.UpStatus},
+		{"запрет сильнее разрешения", func(c *checker.Config) {
+			c.EgressAllow = []string{"127.0.0.0/8"}
+			c.EgressDeny = []string{"127.0.0.1"}
+		}, mock.URL, data.BlockedStatus},
+		{"запрещённый домен", func(c *checker.Config) {
+			c.EgressAllowPrivate = true
+			c.EgressDeny = []string{"localhost"}
+		}, "http://localhost:1/", data.BlockedStatus},
+		{"перенаправление во внутреннюю сеть", func(c *checker.Config) { c.EgressAllow = []string{"127.0.0.0/8"} }, mock.URL + "/internal", data.BlockedStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := checker.DefaultConfig()
+			if tt.config != nil {
+				tt.config(&cfg)
+			}
+			engine := checker.NewEngine(cfg)
+			defer engine.Close()
+
+			res := engine.Check(tt.url)
+			if res.State != tt.status {
+				t.Fatalf("ожидали состояние %q, получили %+v", tt.status, res)
+			}
+			if res.State == data.BlockedStatus && (res.ErrorKind != data.ErrorKindBlocked || res.Attempts != 1) {
+				t.Errorf("ожидали причину blocked без повторов, получили %+v", res)
+			}
+		})
+	}
+}
+
+func TestCheckPostBlocked(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	// движок с правилами по умолчанию
+	engine := checker.NewEngine(checker.DefaultConfig())
+	defer engine.Close()
+	h := api.NewHandlers(data.NewStorage(), engine)
+
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check",
+		bytes.NewBufferString(`{"links": ["`+mock.URL+`/ok"]}`)))
+
+	var resp api.ResponseLinks
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+
+	// старые клиенты видят запрет как недоступность
+	if resp.States[mock.URL+"/ok"] != api.BlockedStatus || resp.Links[mock.URL+"/ok"] != api.NotAvailableStatus {
+		t.Errorf("ожидали состояние blocked и статус not available, получили %v %v", resp.States, resp.Links)
+	}
+}
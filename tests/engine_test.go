@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestEngineLimits(t *testing.T) {
+	var current, peak atomic.Int32
+
+	// ресурс отслеживает, сколько запросов к нему идёт одновременно
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		current.Add(-1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	cfg := testConfig()
+	cfg.Workers = 8
+	cfg.PerHost = 2
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	// два параллельных набора к одному хосту
+	var wg sync.WaitGroup
+	for b := 0; b < 2; b++ {
+		links := make([]data.Link, 10)
+		for i := range links {
+			links[i] = data.Link{Url: fmt.Sprintf("%s/%d/%d", mock.URL, b, i)}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results := engine.Run(links, nil)
+			if len(results) != len(links) {
+				t.Errorf("ожидали %d результатов, получили %d", len(links), len(results))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > int32(cfg.PerHost) {
+		t.Errorf("к одному хосту одновременно шло %d проверок при ограничении %d", p, cfg.PerHost)
+	}
+}
+
+func TestEngineRetries(t *testing.T) {
+	var hits atomic.Int32
+
+	// первые два запроса падают с 503, дальше ресурс отвечает
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	cfg := testConfig()
+	cfg.BackoffBase = time.Millisecond
+	cfg.BackoffMax = 5 * time.Millisecond
+
+	t.Run("flaky", func(t *testing.T) {
+		hits.Store(0)
+		cfg.Retries = 3
+		engine := checker.NewEngine(cfg)
+		defer engine.Close()
+
+		res := engine.Check(mock.URL)
+		if res.Status != "available" || res.Attempts != 3 || !res.Flaky {
+			t.Errorf("ожидали доступность с 3 попытки, получили %+v", res)
+		}
+	})
+
+	t.Run("down", func(t *testing.T) {
+		hits.Store(0)
+		cfg.Retries = 1
+		engine := checker.NewEngine(cfg)
+		defer engine.Close()
+
+		res := engine.Check(mock.URL)
+		if res.Status != "not available" || res.Attempts != 2 || res.Flaky {
+			t.Errorf("ожидали недоступность после 2 попыток, получили %+v", res)
+		}
+	})
+
+	t.Run("POST без повторов", func(t *testing.T) {
+		hits.Store(0)
+		cfg.Retries = 3
+		engine := checker.NewEngine(cfg)
+		defer engine.Close()
+
+		res := engine.CheckLink(data.Link{Url: mock.URL, Method: "post", Body: "order"})
+		if res.State != data.DownStatus || res.Attempts != 1 || hits.Load() != 1 {
+			t.Errorf("ожидали один запрос POST, получили %d: %+v", hits.Load(), res)
+		}
+	})
+
+	t.Run("POST с retry_unsafe", func(t *testing.T) {
+		hits.Store(0)
+		cfg.Retries = 3
+		engine := checker.NewEngine(cfg)
+		defer engine.Close()
+
+		res := engine.CheckLink(data.Link{Url: mock.URL, Method: "POST", RetryUnsafe: true})
+		if res.State != data.DegradedStatus || res.Attempts != 3 {
+			t.Errorf("ожидали доступность с 3 попытки, получили %+v", res)
+		}
+	})
+}
+
+func TestEngineHeadFallback(t *testing.T) {
+	var methods []string
+	var ranges []string
+	var mu sync.Mutex
+
+	// ресурс не поддерживает HEAD
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write(make([]byte, 1<<20))
+	}))
+	defer mock.Close()
+
+	cfg := testConfig()
+	cfg.RangeGET = true
+	cfg.MaxBodyBytes = 1024
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	res := engine.Check(mock.URL)
+	if res.Status != "available" || res.Method != http.MethodGet {
+		t.Errorf("ожидали доступность через GET, получили %+v", res)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Errorf("ожидали HEAD, затем GET, получили %v", methods)
+	}
+	if ranges[1] != "bytes=0-1023" {
+		t.Errorf("ожидали Range bytes=0-1023, получили %q", ranges[1])
+	}
+}
+
+func TestEngineRegisterScheme(t *testing.T) {
+	engine := checker.NewEngine(testConfig())
+	defer engine.Close()
+
+	// новая схема подключается без правок обработчиков
+	engine.Register("mock", checker.CheckerFunc(func(ctx context.Context, link data.Link) data.CheckResult {
+		return data.CheckResult{Url: link.Url, Status: data.AvailableStatus}
+	}))
+
+	results := engine.Run([]data.Link{{Url: "mock://anything"}, {Url: "gopher://old.host"}}, nil)
+
+	if res := results["mock://anything"]; res.Status != data.AvailableStatus || res.Protocol != "mock" {
+		t.Errorf("ожидали проверку зарегистрированной схемой, получили %+v", res)
+	}
+	if res := results["gopher://old.host"]; res.ErrorKind != data.ErrorKindUnsupported || res.Attempts != 1 {
+		t.Errorf("ожидали неподдерживаемую схему без повторов, получили %+v", res)
+	}
+}
+
+func TestEngineRunCancel(t *testing.T) {
+	var checked atomic.Int32
+
+	cfg := testConfig()
+	cfg.Workers = 1
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	engine.Register("mock", checker.CheckerFunc(func(_ context.Context, link data.Link) data.CheckResult {
+		// после первой проверки клиент уходит
+		checked.Add(1)
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		return data.CheckResult{Url: link.Url, Status: data.AvailableStatus}
+	}))
+
+	links := make([]data.Link, 10)
+	for i := range links {
+		links[i] = data.Link{Url: fmt.Sprintf("mock://host%d", i)}
+	}
+
+	results := engine.RunContext(ctx, links, nil)
+	if len(results) == len(links) {
+		t.Errorf("ожидали неполный набор после отмены, получили %d результатов", len(results))
+	}
+
+	// снятые с очереди ссылки не проверяются
+	time.Sleep(50 * time.Millisecond)
+	if n := checked.Load(); n > 2 {
+		t.Errorf("после отмены проверено %d ссылок", n)
+	}
+}
+
+func TestEngineCloseWakesRun(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workers = 1
+	engine := checker.NewEngine(cfg)
+
+	started := make(chan struct{}, 1)
+	engine.Register("mock", checker.CheckerFunc(func(_ context.Context, link data.Link) data.CheckResult {
+		started <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+		return data.CheckResult{Url: link.Url, Status: data.AvailableStatus}
+	}))
+
+	links := []data.Link{{Url: "mock://a"}, {Url: "mock://b"}, {Url: "mock://c"}}
+
+	done := make(chan map[string]data.CheckResult)
+	go func() {
+		done <- engine.Run(links, nil)
+	}()
+
+	// движок закрывают, пока идёт первая проверка
+	<-started
+	engine.Close()
+
+	select {
+	case results := <-done:
+		if len(results) != len(links) {
+			t.Fatalf("ожидали %d результатов, получили %d", len(links), len(results))
+		}
+		if res := results["mock://a"]; res.State != data.UpStatus {
+			t.Errorf("начатая проверка должна доработать, получили %+v", res)
+		}
+		for _, url := range []string{"mock://b", "mock://c"} {
+			if res := results[url]; res.ErrorKind != data.ErrorKindOther || res.State != data.DownStatus {
+				t.Errorf("ожидали ошибку остановленного движка для %s, получили %+v", url, res)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run не вернулся после Close")
+	}
+
+	// после закрытия Run сразу возвращает результаты с ошибкой
+	if results := engine.Run([]data.Link{{Url: "mock://d"}}, nil); results["mock://d"].ErrorKind != data.ErrorKindOther {
+		t.Errorf("ожидали ошибку остановленного движка, получили %+v", results["mock://d"])
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"verifi-server/data"
+)
+
+// startHealthServer h2c сервер с grpc.health.v1.Health/Check:
+// сервис "api" работает, "db" не работает, "cache" в неизвестном состоянии, остальные не найдены
+func startHealthServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != "/grpc.health.v1.Health/Check" ||
+			r.Header.Get("Content-Type") != "application/grpc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		service := ""
+		if len(body) > 7 {
+			service = string(body[7:])
+		}
+
+		statuses := map[string]byte{"": 1, "api": 1, "db": 2, "cache": 0}
+		status, ok := statuses[service]
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+
+		// HealthCheckResponse{status}: поле 1 varint, значение UNKNOWN не передаётся
+		msg := []byte{}
+		if status != 0 {
+			msg = []byte{0x08, status}
+		}
+		w.Write(append([]byte{0, 0, 0, 0, byte(len(msg))}, msg...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+
+	return server
+}
+
+func TestCheckGRPCHealth(t *testing.T) {
+	server := startHealthServer(t)
+	defer server.Close()
+
+	addr := "grpc://" + strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name   string
+		url    string
+		status data.Status
+		health string
+		kind   string
+	}{
+		{"сервер целиком", addr, data.UpStatus, "SERVING", ""},
+		{"сервис работает", addr + "/api", data.UpStatus, "SERVING", ""},
+		{"сервис не работает", addr + "/db", data.DownStatus, "NOT_SERVING", data.ErrorKindStatus},
+		{"состояние неизвестно", addr + "/cache", data.DegradedStatus, "UNKNOWN", ""},
+		{"сервис не найден", addr + "/mail", data.DownStatus, "SERVICE_UNKNOWN", data.ErrorKindStatus},
+		{"без порта", "grpc://127.0.0.1/api", data.InvalidStatus, "", data.ErrorKindInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(data.Link{Url: tt.url})
+
+			if res.State != tt.status || res.Health != tt.health || res.ErrorKind != tt.kind {
+				t.Errorf("ожидали %q/%q (%q), получили %+v", tt.status, tt.health, tt.kind, res)
+			}
+			if res.Protocol != "grpc" {
+				t.Errorf("ожидали протокол grpc, получили %q", res.Protocol)
+			}
+		})
+	}
+}
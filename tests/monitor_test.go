@@ -0,0 +1,372 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"verifi-server/api"
+	"verifi-server/data"
+)
+
+func TestCronNext(t *testing.T) {
+	// суббота, 18 октября 2025, 10:07
+	from := time.Date(2025, 10, 18, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 10, 18, 10, 15, 0, 0, time.UTC)},
+		{"7 * * * *", time.Date(2025, 10, 18, 11, 7, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2025, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"30 2 1 jan,jul *", time.Date(2026, 1, 1, 2, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)},
+		// день месяца или день недели, как в классическом cron
+		{"0 0 13 * fri", time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := data.ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cron.Next(from); !got.Equal(tt.want) {
+				t.Errorf("ожидали %v, получили %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := data.ParseCron(expr); err == nil {
+			t.Errorf("ожидали ошибку для %q", expr)
+		}
+	}
+}
+
+func TestMonitorValidate(t *testing.T) {
+	links := []data.Link{{Url: "http://example.com"}}
+
+	tests := []struct {
+		name    string
+		monitor data.Monitor
+		ok      bool
+	}{
+		{"интервал", data.Monitor{Links: links, Interval: data.Duration(time.Minute)}, true},
+		{"cron", data.Monitor{Links: links, Cron: "*/5 * * * *"}, true},
+		{"без расписания", data.Monitor{Links: links}, false},
+		{"и интервал, и cron", data.Monitor{Links: links, Interval: data.Duration(time.Minute), Cron: "@hourly"}, false},
+		{"слишком частый", data.Monitor{Links: links, Interval: data.Duration(time.Second)}, false},
+		{"никогда не срабатывает", data.Monitor{Links: links, Cron: "0 0 30 2 *"}, false},
+		{"без ссылок", data.Monitor{Interval: data.Duration(time.Minute)}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.monitor.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: ожидали ok=%v, получили %v", tt.name, tt.ok, err)
+		}
+	}
+}
+
+func TestMonitorOverall(t *testing.T) {
+	tests := []struct {
+		name   string
+		states map[string]data.Status
+		want   data.Status
+	}{
+		{"прогонов нет", nil, data.UnknownStatus},
+		{"все up", map[string]data.Status{"a": data.UpStatus, "b": data.UpStatus}, data.UpStatus},
+		{"есть degraded", map[string]data.Status{"a": data.UpStatus, "b": data.DegradedStatus}, data.DegradedStatus},
+		{"есть blocked", map[string]data.Status{"a": data.DegradedStatus, "b": data.BlockedStatus}, data.UnknownStatus},
+		{"есть invalid", map[string]data.Status{"a": data.UpStatus, "b": data.InvalidStatus}, data.UnknownStatus},
+		{"есть unknown", map[string]data.Status{"a": data.UnknownStatus, "b": data.DegradedStatus}, data.UnknownStatus},
+		{"есть down", map[string]data.Status{"a": data.BlockedStatus, "b": data.DownStatus}, data.DownStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := data.Overall(tt.states); got != tt.want {
+				t.Errorf("ожидали %q, получили %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMonitorAPI(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	store := data.NewStorage()
+	h := api.NewHandlers(store, testEngine)
+	defer h.StopMonitors()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/monitors", h.MonitorPostHandler)
+	mux.HandleFunc("GET /api/monitors", h.MonitorListHandler)
+	mux.HandleFunc("GET /api/monitors/{id}", h.MonitorGetHandler)
+	mux.HandleFunc("DELETE /api/monitors/{id}", h.MonitorDeleteHandler)
+	mux.HandleFunc("GET /api/monitors/{id}/runs", h.MonitorRunsHandler)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rec
+	}
+
+	// некорректные мониторы не заводятся
+	for _, body := range []string{
+		`{"links": ["` + mock.URL + `"]}`,
+		`{"links": ["` + mock.URL + `"], "interval": "1s"}`,
+		`{"links": ["` + mock.URL + `"], "cron": "* * *"}`,
+		`{"links": [], "interval": "1m"}`,
+	} {
+		if rec := do(http.MethodPost, "/api/monitors", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("ожидали 400 для %s, получили %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+
+	body := `{"name": "site", "interval": "1m", "links": ["` + mock.URL + `/ok", "` + mock.URL + `/down",
+		{"url": "` + mock.URL + `/auth", "auth": {"username": "monitor", "password": "s3cr3t-pass"}}]}`
+	rec := do(http.MethodPost, "/api/monitors", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("ожидали 201, получили %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "s3cr3t-pass") {
+		t.Errorf("пароль попал в ответ: %s", rec.Body.String())
+	}
+
+	var created api.ResponseMonitor
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/monitors/%d", created.ID)
+
+	// первый прогон идёт сразу после заведения
+	var view api.ResponseMonitor
+	deadline := time.Now().Add(5 * time.Second)
+	for view.LastRun == nil && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		view = api.ResponseMonitor{}
+		json.Unmarshal(do(http.MethodGet, path, "").Body.Bytes(), &view)
+	}
+	if view.LastRun == nil {
+		t.Fatal("первый прогон не состоялся")
+	}
+
+	run := view.LastRun
+	if view.State != data.DownStatus || run.States[mock.URL+"/ok"] != data.UpStatus || run.States[mock.URL+"/down"] != data.DownStatus {
+		t.Errorf("неверные состояния прогона: %s %v", view.State, run.States)
+	}
+	if results, ok := store.GetResults(run.LinksNum); !ok || len(results) != 3 {
+		t.Errorf("результаты прогона не сохранены набором %d", run.LinksNum)
+	}
+
+	// следующий прогон - через интервал после первого
+	if view.NextRun == nil || view.NextRun.Sub(run.StartedAt) < 59*time.Second {
+		t.Errorf("ожидали следующий прогон через минуту после %v, получили %v", run.StartedAt, view.NextRun)
+	}
+
+	var history api.ResponseRuns
+	json.Unmarshal(do(http.MethodGet, path+"/runs", "").Body.Bytes(), &history)
+	if len(history.Runs) != 1 || history.Runs[0].LinksNum != run.LinksNum {
+		t.Errorf("ожидали один прогон в истории, получили %+v", history)
+	}
+
+	var list []api.ResponseMonitor
+	json.Unmarshal(do(http.MethodGet, "/api/monitors", "").Body.Bytes(), &list)
+	if len(list) != 1 || list[0].Name != "site" {
+		t.Errorf("ожидали один монитор в списке, получили %+v", list)
+	}
+
+	// после удаления монитора нет
+	if rec := do(http.MethodDelete, path, ""); rec.Code != http.StatusOK {
+		t.Errorf("ожидали 200 при удалении, получили %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("ожидали 404 после удаления, получили %d", rec.Code)
+	}
+	if _, ok := store.GetResults(run.LinksNum); ok {
+		t.Errorf("набор %d прогона остался после удаления монитора", run.LinksNum)
+	}
+}
+
+func TestFileMonitorStorageReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	ms, err := data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, _ := ms.SaveMonitor(data.Monitor{Name: "kept", Links: []data.Link{{Url: "http://a.com"}}, Cron: "@hourly"})
+	gone, _ := ms.SaveMonitor(data.Monitor{Name: "gone", Links: []data.Link{{Url: "http://b.com"}}, Cron: "@daily"})
+	for i := 1; i <= 3; i++ {
+		if _, err := ms.AppendRun(kept.ID, data.MonitorRun{LinksNum: i, State: data.UpStatus}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ms.DeleteMonitor(gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.AppendRun(gone.ID, data.MonitorRun{LinksNum: 9}); err == nil {
+		t.Error("ожидали ошибку при прогоне удалённого монитора")
+	}
+	ms.Close()
+
+	// после перезапуска мониторы, история и нумерация восстанавливаются
+	ms, err = data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+
+	if list := ms.ListMonitors(); len(list) != 1 || list[0].Name != "kept" {
+		t.Fatalf("ожидали один монитор kept, получили %+v", list)
+	}
+	runs, ok := ms.Runs(kept.ID, 2)
+	if !ok || len(runs) != 2 || runs[0].LinksNum != 3 || runs[1].LinksNum != 2 {
+		t.Errorf("ожидали два последних прогона, новые первыми, получили %+v", runs)
+	}
+	if next, _ := ms.SaveMonitor(data.Monitor{Name: "new"}); next.ID != gone.ID+1 {
+		t.Errorf("ожидали номер %d, получили %d", gone.ID+1, next.ID)
+	}
+}
+
+func TestMonitorHistoryDropsRuns(t *testing.T) {
+	ms := data.NewMonitorStorage()
+	m, _ := ms.SaveMonitor(data.Monitor{Name: "site"})
+
+	for i := 1; i <= data.MonitorHistory; i++ {
+		if dropped, err := ms.AppendRun(m.ID, data.MonitorRun{LinksNum: i}); err != nil || len(dropped) != 0 {
+			t.Fatalf("прогон %d: вытеснены %+v, ошибка %v", i, dropped, err)
+		}
+	}
+
+	// прогон сверх истории вытесняет самый старый, чтобы его набор можно было удалить
+	dropped, err := ms.AppendRun(m.ID, data.MonitorRun{LinksNum: data.MonitorHistory + 1})
+	if err != nil || len(dropped) != 1 || dropped[0].LinksNum != 1 {
+		t.Errorf("ожидали вытесненный прогон 1, получили %+v, ошибка %v", dropped, err)
+	}
+	if runs, _ := ms.Runs(m.ID, 0); len(runs) != data.MonitorHistory || runs[len(runs)-1].LinksNum != 2 {
+		t.Errorf("ожидали %d прогонов, начиная со второго", data.MonitorHistory)
+	}
+}
+
+func TestFileMonitorStorageCompaction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monitors.log")
+	lines := func() int {
+		raw, _ := os.ReadFile(path)
+		return bytes.Count(raw, []byte("\n"))
+	}
+
+	ms, err := data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := ms.SaveMonitor(data.Monitor{Name: "kept"})
+	gone, _ := ms.SaveMonitor(data.Monitor{Name: "gone"})
+	ms.DeleteMonitor(gone.ID)
+
+	// прогонов намного больше истории: журнал переписывается на ходу
+	total := data.MonitorHistory * 3
+	for i := 1; i <= total; i++ {
+		if _, err := ms.AppendRun(m.ID, data.MonitorRun{LinksNum: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lines(); n >= total {
+		t.Errorf("журнал не переписывался: %d записей на %d прогонов", n, total)
+	}
+	ms.Close()
+
+	// переписанный журнал восстанавливается: монитор, его история и нумерация
+	ms, err = data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := ms.ListMonitors(); len(list) != 1 || list[0].Name != "kept" {
+		t.Errorf("ожидали монитор kept, получили %+v", list)
+	}
+	runs, _ := ms.Runs(m.ID, 0)
+	if len(runs) != data.MonitorHistory || runs[0].LinksNum != total || runs[len(runs)-1].LinksNum != total-data.MonitorHistory+1 {
+		t.Errorf("ожидали последние %d прогонов, получили %d", data.MonitorHistory, len(runs))
+	}
+	if next, _ := ms.SaveMonitor(data.Monitor{Name: "new"}); next.ID != gone.ID+1 {
+		t.Errorf("после сжатия журнала ожидали номер %d, получили %d", gone.ID+1, next.ID)
+	}
+	ms.Close()
+
+	// журнал, выросший без сжатия, переписывается при открытии
+	var raw bytes.Buffer
+	for id := 1; id <= 1500; id++ {
+		fmt.Fprintf(&raw, `{"id":%d,"reserved":true}`+"\n", id)
+	}
+	if err := os.WriteFile(path, raw.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err = data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+
+	if n := lines(); n != 1 {
+		t.Errorf("ожидали одну отметку резерва в журнале, получили %d записей", n)
+	}
+	if next, _ := ms.SaveMonitor(data.Monitor{Name: "after"}); next.ID != 1501 {
+		t.Errorf("ожидали номер 1501, получили %d", next.ID)
+	}
+}
+
+func TestFileMonitorStorageReplayCorrupted(t *testing.T) {
+	dir := t.TempDir()
+
+	ms, err := data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := ms.SaveMonitor(data.Monitor{Name: "first"})
+	ms.Close()
+
+	// повреждённая целая строка в середине и оборванная запись в конце
+	file, err := os.OpenFile(filepath.Join(dir, "monitors.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{мусор\n" + `{"id":2,"monitor":{"id":2,"name":"second"}}` + "\n" + `{"id":3,"mon`)
+	file.Close()
+
+	ms, err = data.OpenFileMonitorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+
+	list := ms.ListMonitors()
+	if len(list) != 2 || list[0].ID != first.ID || list[1].Name != "second" {
+		t.Errorf("ожидали мониторы first и second, получили %+v", list)
+	}
+	if next, _ := ms.SaveMonitor(data.Monitor{Name: "third"}); next.ID != 3 {
+		t.Errorf("ожидали номер 3, получили %d", next.ID)
+	}
+}
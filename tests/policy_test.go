@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"verifi-server/api"
+	"verifi-server/data"
+	"verifi-server/server"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/unauth":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer mock.Close()
+
+	server.Srv.Mu.Lock()
+	server.Srv.IsShutdown = false
+	server.Srv.Mu.Unlock()
+
+	store := data.NewStorage()
+	h := api.NewHandlers(store, testEngine)
+
+	// общие правила допускают 401, у отдельных ссылок - свои
+	body := `{
+		"links": [
+			"` + mock.URL + `/unauth",
+			{"url": "` + mock.URL + `/redirect", "policy": {"follow_redirects": false, "expected_status": [200]}},
+			{"url": "` + mock.URL + `/slow", "policy": {"timeout": "50ms"}}
+		],
+		"policy": {"expected_status": ["2xx", 401]},
+		"detailed": true
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ожидали статус %d, получили %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp api.ResponseLinks
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+
+	unauth := resp.Results[mock.URL+"/unauth"]
+	if unauth.Status != api.AvailableStatus || unauth.StatusCode != http.StatusUnauthorized {
+		t.Errorf("401 должен считаться доступным: %+v", unauth)
+	}
+
+	redirect := resp.Results[mock.URL+"/redirect"]
+	if redirect.Status != api.NotAvailableStatus || redirect.StatusCode != http.StatusFound {
+		t.Errorf("без перенаправлений ожидали 302 и недоступность: %+v", redirect)
+	}
+
+	slow := resp.Results[mock.URL+"/slow"]
+	if slow.Status != api.NotAvailableStatus || slow.ErrorKind != data.ErrorKindTimeout {
+		t.Errorf("ожидали таймаут по правилу ссылки: %+v", slow)
+	}
+
+	// правила сохраняются вместе с результатом
+	stored, _ := store.GetResults(resp.LinksNum)
+	policy := stored[mock.URL+"/redirect"].Policy
+	if policy == nil || policy.FollowRedirects == nil || *policy.FollowRedirects || len(policy.ExpectedStatus) != 1 {
+		t.Errorf("в хранилище не записаны правила ссылки: %+v", policy)
+	}
+}
+
+func TestCheckPolicyInvalid(t *testing.T) {
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
+	body := `{"links": ["google.com"], "policy": {"expected_status": ["7xx"]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ожидали статус %d, получили %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCheckRedirectChain(t *testing.T) {
+	parked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer parked.Close()
+
+	// тот же сервер, но под другим именем хоста
+	parkedURL := strings.Replace(parked.URL, "127.0.0.1", "localhost", 1)
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			http.Redirect(w, r, parkedURL+"/landing", http.StatusFound)
+		}
+	}))
+	defer mock.Close()
+
+	res := testEngine.Check(mock.URL + "/old")
+
+	if res.Status != data.AvailableStatus || res.FinalURL != parkedURL+"/landing" {
+		t.Fatalf("ожидали доступность по конечному адресу, получили %+v", res)
+	}
+	if len(res.Redirects) != 2 {
+		t.Fatalf("ожидали 2 перенаправления, получили %+v", res.Redirects)
+	}
+	if res.Redirects[0].URL != mock.URL+"/old" || res.Redirects[0].StatusCode != http.StatusMovedPermanently {
+		t.Errorf("неожиданное первое звено: %+v", res.Redirects[0])
+	}
+	if res.Redirects[1].Location != parkedURL+"/landing" || res.Redirects[1].StatusCode != http.StatusFound {
+		t.Errorf("неожиданное второе звено: %+v", res.Redirects[1])
+	}
+	if !res.CrossDomainRedirect || res.HTTPSDowngrade {
+		t.Errorf("ожидали перенаправление на другой домен без понижения: %+v", res)
+	}
+}
@@ -0,0 +1,313 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"verifi-server/api"
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+// startHTTPProxy http прокси с CONNECT и пересылкой обычных запросов;
+// при заданном auth требует Proxy-Authorization с этими user:pass
+func startHTTPProxy(t *testing.T, auth string, used *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth != "" && r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		used.Add(1)
+
+		if r.Method == http.MethodConnect {
+			target, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, rw, _ := w.(http.Hijacker).Hijack()
+			go func() {
+				io.Copy(target, rw)
+				target.Close()
+			}()
+			io.Copy(conn, target)
+			conn.Close()
+			return
+		}
+
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+}
+
+// startSocksProxy SOCKS5 прокси с логином и паролем user:pass
+func startSocksProxy(t *testing.T, used *atomic.Int32) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 512)
+
+				// приветствие и логин/пароль
+				io.ReadFull(conn, buf[:2])
+				io.ReadFull(conn, buf[:buf[1]])
+				conn.Write([]byte{0x05, 0x02})
+				io.ReadFull(conn, buf[:2])
+				user := make([]byte, buf[1])
+				io.ReadFull(conn, user)
+				io.ReadFull(conn, buf[:1])
+				pass := make([]byte, buf[0])
+				io.ReadFull(conn, pass)
+				if string(user) != "user" || string(pass) != "pass" {
+					conn.Write([]byte{0x01, 0x01})
+					return
+				}
+				conn.Write([]byte{0x01, 0x00})
+
+				// CONNECT по имени хоста
+				io.ReadFull(conn, buf[:5])
+				host := make([]byte, buf[4])
+				io.ReadFull(conn, host)
+				io.ReadFull(conn, buf[:2])
+				port := binary.BigEndian.Uint16(buf[:2])
+
+				target, err := net.Dial("tcp", net.JoinHostPort(string(host), strconv.Itoa(int(port))))
+				if err != nil {
+					conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				used.Add(1)
+				conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return ln
+}
+
+func TestCheckViaProxy(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+	banner := startBannerServer(t)
+	defer banner.Close()
+
+	var httpUsed, socksUsed atomic.Int32
+	httpProxy := startHTTPProxy(t, "user:pass", &httpUsed)
+	defer httpProxy.Close()
+	socks := startSocksProxy(t, &socksUsed)
+	defer socks.Close()
+
+	withAuth := "http://user:pass@" + strings.TrimPrefix(httpProxy.URL, "http://")
+	withSocks := "socks5://user:pass@" + socks.Addr().String()
+	tcpAddr := "tcp://localhost:" + strconv.Itoa(banner.Addr().(*net.TCPAddr).Port)
+
+	// ссылки могут выбирать только прокси, настроенные администратором
+	cfg := testConfig()
+	cfg.ProxyRules = []string{"http.proxy.test=" + withAuth, "socks.proxy.test=" + withSocks}
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	tests := []struct {
+		name   string
+		link   data.Link
+		status string
+		kind   string
+		used   *atomic.Int32
+	}{
+		{"http через http прокси", data.Link{Url: mock.URL + "/ok", Proxy: withAuth}, data.AvailableStatus, "", &httpUsed},
+		{"tcp через CONNECT", data.Link{Url: tcpAddr, Expect: `^\+OK`, Proxy: withAuth}, data.AvailableStatus, "", &httpUsed},
+		{"tcp через socks5", data.Link{Url: tcpAddr, Expect: `^\+OK`, Proxy: withSocks}, data.AvailableStatus, "", &socksUsed},
+		{"неверный пароль", data.Link{Url: tcpAddr, Proxy: httpProxy.URL}, data.NotAvailableStatus, data.ErrorKindProxy, nil},
+		{"неверный пароль socks5", data.Link{Url: tcpAddr, Proxy: "socks5://user:bad@" + socks.Addr().String()}, data.NotAvailableStatus, data.ErrorKindProxy, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := int32(0)
+			if tt.used != nil {
+				before = tt.used.Load()
+			}
+
+			res := engine.CheckLink(tt.link)
+
+			if res.Status != tt.status || res.ErrorKind != tt.kind {
+				t.Fatalf("ожидали %q (%q), получили %+v", tt.status, tt.kind, res)
+			}
+			if tt.used != nil && tt.used.Load() == before {
+				t.Error("проверка прошла мимо прокси")
+			}
+			if res.Proxy == "" || strings.Contains(res.Proxy, "pass") {
+				t.Errorf("ожидали адрес прокси без пароля, получили %q", res.Proxy)
+			}
+		})
+	}
+}
+
+func TestProxyRulesAndCompare(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	var used atomic.Int32
+	proxy := startHTTPProxy(t, "", &used)
+	defer proxy.Close()
+
+	port := strings.TrimPrefix(mock.URL, "http://127.0.0.1:")
+
+	// прокси, который не работает
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	cfg := testConfig()
+	cfg.Proxy = proxy.URL
+	cfg.ProxyRules = []string{"localhost=direct", "dead.proxy.test=" + dead.URL}
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	// общий прокси
+	res := engine.Check(mock.URL + "/ok")
+	if res.Status != data.AvailableStatus || res.Proxy != proxy.URL || used.Load() != 1 {
+		t.Errorf("ожидали проверку через общий прокси, получили %+v", res)
+	}
+
+	// правило для домена
+	res = engine.Check("http://localhost:" + port + "/ok")
+	if res.Status != data.AvailableStatus || res.Proxy != "" || used.Load() != 1 {
+		t.Errorf("ожидали проверку напрямую по правилу, получили %+v", res)
+	}
+
+	// прокси не работает, а напрямую ресурс доступен
+	res = engine.CheckLink(data.Link{Url: mock.URL + "/ok", Proxy: dead.URL, CompareDirect: true})
+	if res.Status != data.NotAvailableStatus || res.ErrorKind != data.ErrorKindProxy {
+		t.Errorf("ожидали отказ прокси, получили %+v", res)
+	}
+	if res.Direct == nil || res.Direct.Status != data.AvailableStatus || res.Direct.Proxy != "" {
+		t.Errorf("ожидали доступность напрямую, получили %+v", res.Direct)
+	}
+}
+
+func TestProxyRestrictions(t *testing.T) {
+	var used atomic.Int32
+	proxy := startHTTPProxy(t, "", &used)
+	defer proxy.Close()
+	stranger := startHTTPProxy(t, "", &used)
+	defer stranger.Close()
+
+	// внутренние адреса не разрешены: ссылки через прокси не должны до них добраться
+	cfg := testConfig()
+	cfg.EgressAllow = nil
+	cfg.Proxy = proxy.URL
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	tests := []struct {
+		name string
+		link data.Link
+	}{
+		{"localhost", data.Link{Url: "http://localhost:6379/"}},
+		{"облачные метаданные", data.Link{Url: "http://metadata.google.internal/computeMetadata/v1/"}},
+		{"локальная сеть", data.Link{Url: "http://printer.local/"}},
+		{"имя без домена", data.Link{Url: "http://redis:6379/"}},
+		{"внутренний IP", data.Link{Url: "http://169.254.169.254/"}},
+		{"прокси не из настроенных", data.Link{Url: "http://example.com/", Proxy: stranger.URL}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := engine.CheckLink(tt.link)
+
+			if res.State != data.BlockedStatus || res.ErrorKind != data.ErrorKindBlocked {
+				t.Fatalf("ожидали blocked, получили %+v", res)
+			}
+		})
+	}
+
+	if n := used.Load(); n != 0 {
+		t.Errorf("запрещённые проверки прошли через прокси %d раз", n)
+	}
+
+	// настроенный прокси можно выбрать и в запросе
+	if !engine.HasProxy(proxy.URL) || !engine.HasProxy(data.ProxyDirect) || engine.HasProxy(stranger.URL) {
+		t.Error("ожидали, что допустимы только настроенный прокси и direct")
+	}
+
+	// запрос с чужим прокси отклоняется до проверок
+	h := api.NewHandlers(data.NewStorage(), engine)
+	rec := httptest.NewRecorder()
+	body := `{"links": ["example.com"], "proxy": "` + stranger.URL + `"}`
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ожидали 400, получили %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestProxyEgressDefaults(t *testing.T) {
+	// прокси во внутренней сети отвечает сам: /redirect уводит на адрес метаданных облака
+	var requested []string
+	var mu sync.Mutex
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.String())
+		mu.Unlock()
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	// правила по умолчанию: внутренние адреса запрещены, но настроенный прокси на loopback работает
+	cfg := checker.DefaultConfig()
+	cfg.Proxy = proxy.URL
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	if res := engine.Check("http://site.example/ok"); res.State != data.UpStatus || res.Proxy != proxy.URL {
+		t.Errorf("ожидали up через прокси на loopback, получили %+v", res)
+	}
+
+	// звено перенаправления сверяется с правилами, хотя его откроет прокси
+	res := engine.Check("http://site.example/redirect")
+	if res.State != data.BlockedStatus || res.ErrorKind != data.ErrorKindBlocked {
+		t.Errorf("ожидали blocked для перенаправления во внутреннюю сеть, получили %+v", res)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, url := range requested {
+		if strings.Contains(url, "169.254.169.254") {
+			t.Errorf("прокси получил запрос во внутреннюю сеть: %s", url)
+		}
+	}
+}
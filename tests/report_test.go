@@ -5,10 +5,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
 	"verifi-server/api"
+	"verifi-server/data"
 )
 
 func TestReportPostHandler_EmptyAndFakeIDs(t *testing.T) {
@@ -32,6 +34,8 @@ func TestReportPostHandler_EmptyAndFakeIDs(t *testing.T) {
 		},
 	}
 
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 1. Создаём HTTP-запрос
@@ -44,7 +48,7 @@ func TestReportPostHandler_EmptyAndFakeIDs(t *testing.T) {
 
 			// 2. Захватываем ответ
 			rec := httptest.NewRecorder()
-			api.ReportPostHandler(rec, req)
+			h.ReportPostHandler(rec, req)
 
 			// 3. Проверяем статус-код
 			if rec.Code != tc.expectStatus {
@@ -71,3 +75,34 @@ func TestReportPostHandler_EmptyAndFakeIDs(t *testing.T) {
 		})
 	}
 }
+
+func TestReportPostHandlerPDF(t *testing.T) {
+	store := data.NewStorage()
+	id, _ := store.SaveResults(map[string]data.CheckResult{
+		"google.com": {Url: "google.com", Status: api.AvailableStatus, StatusCode: 200, LatencyMs: 42},
+		"example.com": {
+			Url:        "example.com",
+			Status:     api.NotAvailableStatus,
+			StatusCode: 200,
+			ErrorKind:  data.ErrorKindContent,
+			Error:      `body contains "Service error"`,
+			Assertions: []data.AssertionResult{{Passed: false}},
+		},
+	})
+
+	h := api.NewHandlers(store, testEngine)
+
+	req := httptest.NewRequest(http.MethodPost, "/report", bytes.NewBufferString(`{"links_list": [`+strconv.Itoa(id)+`]}`))
+	rec := httptest.NewRecorder()
+	h.ReportPostHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ожидали статус %d, получили %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("ожидали Content-Type application/pdf, получили %q", ct)
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")) {
+		t.Error("ответ не похож на PDF")
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"verifi-server/api"
+	"verifi-server/data"
+)
+
+// startAuthServer ресурс, отвечающий 200 только на правильно собранный запрос
+func startAuthServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != `{"probe":true}` || r.Host != "api.internal" ||
+				r.Header.Get("Authorization") != "Bearer s3cr3t-token" || r.Header.Get("X-Api-Key") != "k3y-value" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+
+		case "/basic":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "monitor" || pass != "hunter22" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+
+		case "/leak":
+			// перенаправление с токеном в адресе и ошибка с ним же
+			http.Redirect(w, r, "/fail?access_token=t0ken-in-url", http.StatusFound)
+
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+}
+
+func TestCheckCustomRequest(t *testing.T) {
+	mock := startAuthServer()
+	defer mock.Close()
+
+	login := data.Link{
+		Url:     mock.URL + "/login",
+		Method:  "post",
+		Headers: map[string]string{"Host": "api.internal", "X-Api-Key": "k3y-value", "Content-Type": "application/json"},
+		Body:    `{"probe":true}`,
+		Auth:    &data.Credentials{Token: "s3cr3t-token"},
+	}
+	noToken := login
+	noToken.Auth = nil
+
+	tests := []struct {
+		name   string
+		link   data.Link
+		status string
+		code   int
+	}{
+		{"POST с телом, заголовками и токеном", login, data.AvailableStatus, http.StatusOK},
+		{"без токена", noToken, data.NotAvailableStatus, http.StatusUnauthorized},
+		{"basic авторизация", data.Link{Url: mock.URL + "/basic", Auth: &data.Credentials{Username: "monitor", Password: "hunter22"}}, data.AvailableStatus, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(tt.link)
+			if res.Status != tt.status || res.StatusCode != tt.code {
+				t.Errorf("ожидали %q с кодом %d, получили %+v", tt.status, tt.code, res)
+			}
+		})
+	}
+
+	if res := testEngine.CheckLink(login); res.Method != http.MethodPost {
+		t.Errorf("ожидали метод POST, получили %q", res.Method)
+	}
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	mock := startAuthServer()
+	defer mock.Close()
+
+	host := strings.TrimPrefix(mock.URL, "http://")
+	body := `{"detailed": true, "links": [
+		{"url": "http://monitor:hunter22@` + host + `/basic"},
+		{"url": "` + mock.URL + `/leak?api_key=k3y-in-query", "headers": {"X-Auth-Token": "h3ader-secret"}, "auth": {"token": "s3cr3t-token"}}
+	]}`
+
+	store := data.NewStorage()
+	h := api.NewHandlers(store, testEngine)
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body)))
+
+	var resp api.ResponseLinks
+	raw := rec.Body.Bytes()
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+
+	stored, _ := store.GetResults(resp.LinksNum)
+	storedJSON, _ := json.Marshal(stored)
+
+	for _, secret := range []string{"hunter22", "k3y-in-query", "t0ken-in-url", "h3ader-secret", "s3cr3t-token"} {
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Errorf("секрет %q попал в ответ: %s", secret, raw)
+		}
+		if bytes.Contains(storedJSON, []byte(secret)) {
+			t.Errorf("секрет %q попал в хранилище", secret)
+		}
+	}
+
+	if resp.Links["http://monitor:xxxxx@"+host+"/basic"] != api.AvailableStatus {
+		t.Errorf("ожидали доступную ссылку с замаскированным паролем, получили %v", resp.Links)
+	}
+}
+
+func TestRedirectDropsSecretHeaders(t *testing.T) {
+	got := make(chan http.Header, 2)
+
+	// другой хост, куда уводит перенаправление
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Clone()
+	}))
+	defer other.Close()
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, other.URL+"/landing", http.StatusFound)
+		case "/here":
+			http.Redirect(w, r, "/landing", http.StatusFound)
+		default:
+			got <- r.Header.Clone()
+		}
+	}))
+	defer mock.Close()
+
+	headers := map[string]string{"X-Api-Key": "k3y-value", "X-Trace": "trace-1"}
+
+	// на другой хост секретный заголовок не уходит, обычный - уходит
+	testEngine.CheckLink(data.Link{Url: mock.URL + "/away", Method: http.MethodGet, Headers: headers})
+	if h := <-got; h.Get("X-Api-Key") != "" || h.Get("X-Trace") != "trace-1" {
+		t.Errorf("ожидали без X-Api-Key и с X-Trace, получили %v", h)
+	}
+
+	// в пределах хоста заголовки сохраняются
+	testEngine.CheckLink(data.Link{Url: mock.URL + "/here", Method: http.MethodGet, Headers: headers})
+	if h := <-got; h.Get("X-Api-Key") != "k3y-value" {
+		t.Errorf("ожидали X-Api-Key при перенаправлении на тот же хост, получили %v", h)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestStatusLegacy(t *testing.T) {
+	tests := []struct {
+		state  data.Status
+		legacy string
+	}{
+		{data.UpStatus, data.AvailableStatus},
+		{data.DegradedStatus, data.AvailableStatus},
+		{data.DownStatus, data.NotAvailableStatus},
+		{data.UnknownStatus, data.NotAvailableStatus},
+		{data.BlockedStatus, data.NotAvailableStatus},
+		{data.InvalidStatus, data.NotAvailableStatus},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.Legacy(); got != tt.legacy {
+			t.Errorf("%s: ожидали %q, получили %q", tt.state, tt.legacy, got)
+		}
+		if got := data.ParseStatus(string(tt.state)); got != tt.state {
+			t.Errorf("ожидали %q, получили %q", tt.state, got)
+		}
+	}
+
+	// статусы прежней модели
+	if data.ParseStatus(data.AvailableStatus) != data.UpStatus || data.ParseStatus(data.NotAvailableStatus) != data.DownStatus {
+		t.Error("статусы прежней модели переводятся неверно")
+	}
+	if data.ParseStatus("whatever") != data.UnknownStatus {
+		t.Error("неизвестный статус должен давать unknown")
+	}
+}
+
+func TestStatusReasons(t *testing.T) {
+	var hits atomic.Int32
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/flaky":
+			if hits.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	cfg := testConfig()
+	cfg.Retries = 1
+	cfg.BackoffBase = time.Millisecond
+	cfg.SlowThreshold = 30 * time.Millisecond
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		state  data.Status
+		reason string
+	}{
+		{"отвечает", mock.URL + "/ok", data.UpStatus, ""},
+		{"отвечает медленно", mock.URL + "/slow", data.DegradedStatus, data.ReasonSlow},
+		{"5xx, но восстановился", mock.URL + "/flaky", data.DegradedStatus, data.ReasonFlaky},
+		{"5xx", mock.URL + "/down", data.DownStatus, data.ErrorKindStatus},
+		{"нет проверки для схемы", "gopher://old.host", data.UnknownStatus, data.ErrorKindUnsupported},
+		{"некорректная ссылка", "grpc://127.0.0.1/api", data.InvalidStatus, data.ErrorKindInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := engine.Check(tt.url)
+
+			if res.State != tt.state || res.Status != tt.state.Legacy() {
+				t.Fatalf("ожидали %q (%q), получили %+v", tt.state, tt.state.Legacy(), res)
+			}
+			if tt.reason == "" && len(res.Reasons) != 0 || tt.reason != "" && !slices.Contains(res.Reasons, tt.reason) {
+				t.Errorf("ожидали причину %q, получили %v", tt.reason, res.Reasons)
+			}
+		})
+	}
+}
+
+func TestStatusStoredBefore(t *testing.T) {
+	dir := t.TempDir()
+
+	// журнал, записанный до появления состояний
+	journal := `{"id":1,"results":{"a.com":{"url":"a.com","status":"available"},"b.com":{"url":"b.com","status":"degraded"},` +
+		`"c.com":{"url":"c.com","status":"not available","error_kind":"dns"},"d.com":{"url":"d.com","status":"blocked"}}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "results.log"), []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	results, ok := fs.GetResults(1)
+	if !ok {
+		t.Fatal("набор не восстановлен")
+	}
+
+	want := map[string]data.Status{
+		"a.com": data.UpStatus,
+		"b.com": data.DegradedStatus,
+		"c.com": data.DownStatus,
+		"d.com": data.BlockedStatus,
+	}
+	for url, state := range want {
+		if res := results[url]; res.State != state || res.Status != state.Legacy() {
+			t.Errorf("%s: ожидали %q (%q), получили %q (%q)", url, state, state.Legacy(), res.State, res.Status)
+		}
+	}
+
+	if stats := fs.Stats(); stats.States[data.UpStatus] != 1 || stats.States[data.DownStatus] != 1 {
+		t.Errorf("неверная сводка по состояниям: %v", stats.States)
+	}
+}
@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"verifi-server/data"
+)
+
+func TestFileStorageReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	// сохраняем два набора и закрываем хранилище
+	fs, err := data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := fs.SaveResults(map[string]data.CheckResult{
+		"google.com": {Url: "google.com", Status: "available", StatusCode: 200},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := fs.SaveResults(map[string]data.CheckResult{
+		"gg.c": {Url: "gg.c", Status: "not available", ErrorKind: data.ErrorKindDNS},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// имитируем запись, оборванную сбоем
+	f, err := os.OpenFile(filepath.Join(dir, "results.log"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":3,"results":{"ya.ru":`)
+	f.Close()
+
+	// открываем заново и проверяем, что старые номера на месте
+	fs, err = data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	res, ok := fs.GetResults(first)
+	if !ok || res["google.com"].StatusCode != 200 {
+		t.Errorf("набор %d не восстановлен: %+v", first, res)
+	}
+
+	res, ok = fs.GetResults(second)
+	if !ok || res["gg.c"].ErrorKind != data.ErrorKindDNS {
+		t.Errorf("набор %d не восстановлен: %+v", second, res)
+	}
+
+	// новый номер продолжает счётчик, а не начинается с 1
+	third, err := fs.SaveResults(map[string]data.CheckResult{"ya.ru": {Url: "ya.ru"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third != second+1 {
+		t.Errorf("ожидали номер %d, получили %d", second+1, third)
+	}
+}
+
+func TestStoreListDeleteStats(t *testing.T) {
+	fs, err := data.OpenFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	stores := map[string]data.Store{
+		"память": data.NewStorage(),
+		"файл":   fs,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			a, _ := store.SaveResults(map[string]data.CheckResult{"a.ru": {}, "b.ru": {}})
+			b, _ := store.SaveResults(map[string]data.CheckResult{"c.ru": {}})
+
+			ids := store.ListResults()
+			if len(ids) != 2 || ids[0] != a || ids[1] != b {
+				t.Errorf("ожидали номера [%d %d], получили %v", a, b, ids)
+			}
+
+			stats := store.Stats()
+			if stats.Sets != 2 || stats.Links != 3 || stats.NextID != b+1 {
+				t.Errorf("неожиданная сводка: %+v", stats)
+			}
+
+			deleted, err := store.DeleteResults(a)
+			if err != nil || !deleted {
+				t.Fatalf("не удалось удалить набор %d: %v", a, err)
+			}
+			if _, ok := store.GetResults(a); ok {
+				t.Errorf("набор %d не удалён", a)
+			}
+			if deleted, _ := store.DeleteResults(a); deleted {
+				t.Errorf("повторное удаление набора %d должно вернуть false", a)
+			}
+		})
+	}
+}
+
+func TestFileStorageReplayDeleted(t *testing.T) {
+	dir := t.TempDir()
+
+	fs, err := data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := fs.SaveResults(map[string]data.CheckResult{"a.ru": {}})
+	fs.DeleteResults(id)
+	fs.Close()
+
+	fs, err = data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	if _, ok := fs.GetResults(id); ok {
+		t.Errorf("удалённый набор %d восстановлен из журнала", id)
+	}
+
+	// номер удалённого набора повторно не выдаётся
+	if next := fs.Stats().NextID; next != id+1 {
+		t.Errorf("ожидали следующий номер %d, получили %d", id+1, next)
+	}
+}
+
+func TestFileStorageReplayCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.log")
+
+	fs, err := data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := fs.SaveResults(map[string]data.CheckResult{"a.ru": {Url: "a.ru"}})
+	fs.Close()
+
+	// повреждённая целая строка посреди журнала, за ней целая запись и оборванный хвост
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{испорчено\n")
+	f.WriteString(`{"id":2,"results":{"b.ru":{"url":"b.ru"}}}` + "\n")
+	f.WriteString(`{"id":3,"results":{"c.ru":`)
+	f.Close()
+
+	fs, err = data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	if _, ok := fs.GetResults(first); !ok {
+		t.Errorf("набор %d до повреждённой строки не восстановлен", first)
+	}
+	if res, ok := fs.GetResults(2); !ok || res["b.ru"].Url != "b.ru" {
+		t.Errorf("набор 2 после повреждённой строки потерян: %+v", res)
+	}
+	if _, ok := fs.GetResults(3); ok {
+		t.Errorf("оборванная запись 3 восстановлена")
+	}
+
+	// новая запись ложится сразу за последней целой строкой
+	if _, err := fs.SaveResults(map[string]data.CheckResult{"d.ru": {Url: "d.ru"}}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte(`"c.ru"`)) {
+		t.Errorf("оборванный хвост не отрезан:\n%s", raw)
+	}
+}
+
+func TestFileStorageCompaction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.log")
+	lines := func() int {
+		raw, _ := os.ReadFile(path)
+		return bytes.Count(raw, []byte("\n"))
+	}
+
+	fs, err := data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// наборы сохраняются и удаляются, как у мониторов с заполненной историей:
+	// журнал переписывается на ходу и не растёт вместе с числом удалённых
+	total := 1500
+	var kept int
+	for i := 0; i < total; i++ {
+		id, err := fs.SaveResults(map[string]data.CheckResult{"a.ru": {Url: "a.ru"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 500 {
+			kept = id
+			continue
+		}
+		if _, err := fs.DeleteResults(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lines(); n >= total {
+		t.Errorf("журнал не переписывался: %d записей на %d сохранений и удалений", n, 2*total-1)
+	}
+	fs.Close()
+
+	fs, err = data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := fs.ListResults(); len(ids) != 1 || ids[0] != kept {
+		t.Errorf("ожидали один набор %d, получили %v", kept, ids)
+	}
+
+	// номера удалённых наборов повторно не выдаются
+	if id, _ := fs.SaveResults(map[string]data.CheckResult{}); id != total+1 {
+		t.Errorf("ожидали номер %d, получили %d", total+1, id)
+	}
+	fs.Close()
+
+	// журнал, выросший без сжатия, переписывается при открытии
+	var raw bytes.Buffer
+	for id := 1; id <= total; id++ {
+		fmt.Fprintf(&raw, `{"id":%d,"reserved":true}`+"\n", id)
+	}
+	if err := os.WriteFile(path, raw.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err = data.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	if n := lines(); n != 1 {
+		t.Errorf("ожидали одну отметку резерва в журнале, получили %d записей", n)
+	}
+	if id, _ := fs.SaveResults(map[string]data.CheckResult{}); id != total+1 {
+		t.Errorf("ожидали номер %d, получили %d", total+1, id)
+	}
+}
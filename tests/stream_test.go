@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"verifi-server/api"
+	"verifi-server/data"
+	"verifi-server/server"
+)
+
+func TestCheckStream(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	baseURL := strings.TrimSuffix(mock.URL, "/")
+
+	server.Srv.Mu.Lock()
+	server.Srv.IsShutdown = false
+	server.Srv.Mu.Unlock()
+
+	store := data.NewStorage()
+	h := api.NewHandlers(store, testEngine)
+	body := `{"links": ["` + baseURL + `/ok", "` + baseURL + `/bad"]}`
+
+	t.Run("ndjson", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/check?stream=true", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		h.CheckPostHandler(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Fatalf("ожидали Content-Type application/x-ndjson, получили %q", ct)
+		}
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("ожидали 3 строки (2 ссылки и итог), получили %d:\n%s", len(lines), rec.Body.String())
+		}
+
+		for _, line := range lines[:2] {
+			var res data.CheckResult
+			if err := json.Unmarshal([]byte(line), &res); err != nil || res.Url == "" {
+				t.Errorf("некорректная строка результата %q: %v", line, err)
+			}
+		}
+
+		var summary api.StreamSummary
+		if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+			t.Fatal("не удалось декодировать итог:", err)
+		}
+		if _, ok := store.GetResults(summary.LinksNum); !ok || summary.Total != 2 {
+			t.Errorf("набор из итога не сохранён: %+v", summary)
+		}
+	})
+
+	t.Run("sse", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body))
+		req.Header.Set("Accept", "text/event-stream")
+		rec := httptest.NewRecorder()
+		h.CheckPostHandler(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("ожидали Content-Type text/event-stream, получили %q", ct)
+		}
+
+		var events []string
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events = append(events, name)
+			}
+		}
+
+		want := []string{"result", "result", "done"}
+		if strings.Join(events, ",") != strings.Join(want, ",") {
+			t.Errorf("ожидали события %v, получили %v", want, events)
+		}
+	})
+
+	t.Run("некорректный адрес", func(t *testing.T) {
+		body := `{"links": ["not a url", "` + baseURL + `/ok"]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/check?stream=true", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		h.CheckPostHandler(rec, req)
+
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("ожидали 3 строки (2 адреса и итог), получили %d:\n%s", len(lines), rec.Body.String())
+		}
+
+		// некорректный адрес не проверяется, его результат приходит первым
+		var res data.CheckResult
+		if err := json.Unmarshal([]byte(lines[0]), &res); err != nil || res.Url != "not a url" ||
+			res.State != data.InvalidStatus || res.ErrorKind != data.ErrorKindInvalid {
+			t.Errorf("ожидали invalid для некорректного адреса, получили %s", lines[0])
+		}
+
+		var summary api.StreamSummary
+		if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+			t.Fatal("не удалось декодировать итог:", err)
+		}
+		results, ok := store.GetResults(summary.LinksNum)
+		if !ok || summary.Total != 2 || results["not a url"].State != data.InvalidStatus {
+			t.Errorf("ожидали набор с некорректным адресом в состоянии invalid: %+v %v", summary, results)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"verifi-server/data"
+)
+
+// startBannerServer tcp сервер, который здоровается и отвечает PONG на PING
+func startBannerServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("+OK ready\r\n"))
+
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err == nil && line == "PING\r\n" {
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}()
+		}
+	}()
+
+	return ln
+}
+
+func TestCheckTCP(t *testing.T) {
+	ln := startBannerServer(t)
+	defer ln.Close()
+
+	addr := "tcp://" + ln.Addr().String()
+
+	tests := []struct {
+		name      string
+		link      data.Link
+		available bool
+		kind      string
+	}{
+		{"порт открыт", data.Link{Url: addr}, true, ""},
+		{"приветствие совпало", data.Link{Url: addr, Expect: `^\+OK`}, true, ""},
+		{"ответ на команду", data.Link{Url: addr, Send: "PING\r\n", Expect: `\+PONG`}, true, ""},
+		{"приветствие не совпало", data.Link{Url: addr, Expect: "^SSH-", Policy: &data.Policy{Timeout: data.Duration(200 * time.Millisecond)}}, false, data.ErrorKindContent},
+		{"без порта", data.Link{Url: "tcp://127.0.0.1"}, false, data.ErrorKindInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(tt.link)
+
+			if (res.Status == data.AvailableStatus) != tt.available || res.ErrorKind != tt.kind {
+				t.Errorf("ожидали доступность %v (%q), получили %+v", tt.available, tt.kind, res)
+			}
+			if res.Protocol != "tcp" {
+				t.Errorf("ожидали протокол tcp, получили %q", res.Protocol)
+			}
+		})
+	}
+
+	// закрытый порт
+	ln.Close()
+	res := testEngine.CheckLink(data.Link{Url: addr})
+	if res.Status != data.NotAvailableStatus || res.ErrorKind != data.ErrorKindRefused {
+		t.Errorf("ожидали отказ в соединении, получили %+v", res)
+	}
+}
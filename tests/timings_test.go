@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"verifi-server/api"
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestCheckTimings(t *testing.T) {
+	// ресурс отвечает с задержкой
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	cfg := testConfig()
+	cfg.SlowThreshold = 30 * time.Millisecond
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	t.Run("slow", func(t *testing.T) {
+		res := engine.Check(mock.URL + "/slow")
+		if res.Timings == nil {
+			t.Fatalf("ожидали время по фазам, получили %+v", res)
+		}
+		if res.Timings.TTFBMs < 50 || res.Timings.TotalMs < res.Timings.TTFBMs {
+			t.Errorf("ожидали первый байт не раньше 50 ms и не позже конца проверки, получили %+v", res.Timings)
+		}
+		if !res.Slow {
+			t.Errorf("ожидали отметку медленной ссылки, получили %+v", res)
+		}
+	})
+
+	t.Run("fast", func(t *testing.T) {
+		res := engine.Check(mock.URL + "/fast")
+		if res.Timings == nil || res.Timings.TotalMs <= 0 {
+			t.Fatalf("ожидали время по фазам, получили %+v", res)
+		}
+		if res.Slow {
+			t.Errorf("быстрая ссылка отмечена медленной: %+v", res.Timings)
+		}
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		res := engine.Check("tcp://" + mock.Listener.Addr().String())
+		if res.Timings == nil || res.Timings.ConnectMs <= 0 {
+			t.Errorf("ожидали время TCP подключения, получили %+v", res.Timings)
+		}
+	})
+}
+
+func TestCheckTimingsResponse(t *testing.T) {
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	store := data.NewStorage()
+	h := api.NewHandlers(store, testEngine)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(`{"links": ["`+mock.URL+`"], "detailed": true}`))
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, req)
+
+	var resp struct {
+		LinksNum int `json:"links_num"`
+		Results  map[string]struct {
+			Timings map[string]any `json:"timings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("не удалось разобрать ответ: %v", err)
+	}
+	timings := resp.Results[mock.URL].Timings
+	for _, field := range []string{"dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "total_ms", "reused"} {
+		if _, ok := timings[field]; !ok {
+			t.Errorf("в timings нет поля %s: %v", field, timings)
+		}
+	}
+
+	// отчёт с медленной ссылкой и ссылкой без замеров
+	id, _ := store.SaveResults(map[string]data.CheckResult{
+		"slow.example": {Url: "slow.example", Status: api.AvailableStatus, Slow: true, Timings: &data.Timings{TTFBMs: 1500, TotalMs: 1520}},
+		"old.example":  {Url: "old.example", Status: api.AvailableStatus, LatencyMs: 42},
+	})
+
+	req = httptest.NewRequest(http.MethodPost, "/report", bytes.NewBufferString(`{"links_list": [`+strconv.Itoa(id)+`]}`))
+	rec = httptest.NewRecorder()
+	h.ReportPostHandler(rec, req)
+
+	if rec.Code != http.StatusOK || !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")) {
+		t.Errorf("ожидали pdf отчёт, получили %d: %.200s", rec.Code, rec.Body.String())
+	}
+}
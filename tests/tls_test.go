@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"verifi-server/checker"
+	"verifi-server/data"
+)
+
+func TestCheckTLSSelfSigned(t *testing.T) {
+	mock := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mock.Close()
+
+	res := testEngine.Check(mock.URL)
+
+	if res.Status != data.NotAvailableStatus || res.ErrorKind != data.ErrorKindTLS {
+		t.Fatalf("ожидали ошибку TLS, получили %+v", res)
+	}
+	if res.TLS == nil || len(res.TLS.Chain) == 0 {
+		t.Fatalf("ожидали сведения о сертификате, получили %+v", res.TLS)
+	}
+	if !res.TLS.SelfSigned {
+		t.Error("сертификат тестового сервера должен быть отмечен как самоподписанный")
+	}
+	if res.TLS.DaysLeft <= 0 || len(res.TLS.Chain[0].SANs) == 0 {
+		t.Errorf("неполные сведения о сертификате: %+v", res.TLS.Chain[0])
+	}
+}
+
+func TestCheckTLSExpiring(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, ca.pem, 0o600)
+
+	// сертификату осталось чуть меньше 5 дней
+	mock := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	mock.TLS = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, "expiring.test", 5*24*time.Hour, false)}}
+	mock.StartTLS()
+	defer mock.Close()
+
+	tests := []struct {
+		name     string
+		warnDays int
+		status   data.Status
+	}{
+		{"истекает в пределах CertWarnDays", 14, data.DegradedStatus},
+		{"истекает позже CertWarnDays", 3, data.UpStatus},
+		{"предупреждения выключены", 0, data.UpStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.CertWarnDays = tt.warnDays
+			cfg.TLSProfiles = map[string]checker.TLSProfile{"ca": {CAFile: caFile, ServerName: "expiring.test"}}
+			engine := checker.NewEngine(cfg)
+			defer engine.Close()
+
+			res := engine.CheckLink(data.Link{Url: mock.URL, TLSProfile: "ca"})
+
+			if res.State != tt.status || res.TLS == nil || res.TLS.DaysLeft != 4 {
+				t.Fatalf("ожидали %q и 4 дня до истечения, получили %+v", tt.status, res)
+			}
+			degraded := slices.Contains(res.Reasons, data.ReasonCertExpiring) && res.Warning != ""
+			if degraded != (tt.status == data.DegradedStatus) {
+				t.Errorf("ожидали причину %q только для degraded, получили %+v", data.ReasonCertExpiring, res)
+			}
+		})
+	}
+}
+
+// testCA удостоверяющий центр для выпуска тестовых сертификатов
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA создаёт самоподписанный УЦ
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Verifi Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат на name со сроком validFor; client - для проверки клиента
+func (ca *testCA) issue(t *testing.T, name string, validFor time.Duration, client bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := x509.ExtKeyUsageServerAuth
+	if client {
+		usage = x509.ExtKeyUsageClientAuth
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writePEM записывает сертификат и ключ в файлы и возвращает их пути
+func writePEM(t *testing.T, dir, name string, cert tls.Certificate) (string, string) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	return certFile, keyFile
+}
+
+func TestCheckTLSProfiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, ca.pem, 0o600)
+	certFile, keyFile := writePEM(t, dir, "client", ca.issue(t, "monitor", 24*time.Hour, true))
+
+	// внутренний сервис: сертификат частного УЦ на имя internal.test и обязательный сертификат клиента
+	newServer := func(validFor time.Duration, maxVersion uint16) *httptest.Server {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.Config.ErrorLog = log.New(io.Discard, "", 0) // отказы в рукопожатии здесь ожидаемы
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "internal.test", validFor, false)},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+			MaxVersion:   maxVersion,
+		}
+		server.StartTLS()
+		return server
+	}
+
+	internal := newServer(90*24*time.Hour, 0)
+	defer internal.Close()
+	expiring := newServer(5*24*time.Hour, 0)
+	defer expiring.Close()
+	legacy := newServer(90*24*time.Hour, tls.VersionTLS12)
+	defer legacy.Close()
+
+	cfg := testConfig()
+	cfg.TLSProfiles = map[string]checker.TLSProfile{
+		"internal": {CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "internal.test"},
+		"ca-only":  {CAFile: caFile, ServerName: "internal.test"},
+		"modern":   {CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "internal.test", MinVersion: "1.3"},
+	}
+	engine := checker.NewEngine(cfg)
+	defer engine.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		profile string
+		status  data.Status
+		kind    string
+	}{
+		{"без профиля", internal.URL, "", data.DownStatus, data.ErrorKindTLS},
+		{"частный УЦ и сертификат клиента", internal.URL, "internal", data.UpStatus, ""},
+		{"без сертификата клиента", internal.URL, "ca-only", data.DownStatus, data.ErrorKindTLS},
+		{"сертификат скоро истечёт", expiring.URL, "internal", data.DegradedStatus, ""},
+		{"версия TLS ниже минимальной", legacy.URL, "modern", data.DownStatus, data.ErrorKindTLS},
+		{"неизвестный профиль", internal.URL, "missing", data.InvalidStatus, data.ErrorKindInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := engine.CheckLink(data.Link{Url: tt.url, TLSProfile: tt.profile})
+
+			if res.State != tt.status || res.ErrorKind != tt.kind {
+				t.Fatalf("ожидали %q (%q), получили %+v", tt.status, tt.kind, res)
+			}
+			if res.State == data.DegradedStatus && (res.Warning == "" || res.TLS == nil || res.TLS.DaysLeft > 5) {
+				t.Errorf("ожидали предупреждение об истечении сертификата, получили %+v", res)
+			}
+			if res.State == data.UpStatus && (res.TLS == nil || res.TLS.HostnameMismatch || res.TLSProfile != tt.profile) {
+				t.Errorf("ожидали проверенный сертификат internal.test, получили %+v", res.TLS)
+			}
+		})
+	}
+}
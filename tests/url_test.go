@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"verifi-server/api"
+	"verifi-server/data"
+	"verifi-server/server"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		valid    bool
+	}{
+		{"google.com", "http://google.com", true},
+		{"HTTP://Google.com/", "http://google.com", true},
+		{"  https://google.com:443/search?q=go#top ", "https://google.com/search?q=go", true},
+		{"http://google.com:8080/docs/", "http://google.com:8080/docs/", true},
+		{"http://пример.рф/", "http://xn--e1afmkfd.xn--p1ai", true},
+		{"https://Bücher.example", "https://xn--bcher-kva.example", true},
+		{"http://[::1]:80/", "http://[::1]", true},
+		{"tcp://DB.local:5432", "tcp://db.local:5432", true},
+		{"dns:///example.com?type=A#x", "dns:///example.com?type=A", true},
+		{"dns://8.8.8.8:53/example.com?type=MX", "dns://8.8.8.8/example.com?type=MX", true},
+		{"dns:///", "", false},
+		{"", "", false},
+		{"not a url", "", false},
+		{"http://", "", false},
+		{"http://exa$mple.com", "", false},
+		{"http://example.com:99999", "", false},
+		{"http://-example.com", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := data.NormalizeURL(tt.raw)
+			if (err == nil) != tt.valid {
+				t.Fatalf("ожидали корректность %v, получили %q, %v", tt.valid, got, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ожидали %q, получили %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckPostNormalizesLinks(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	// один и тот же адрес, записанный трижды, и два некорректных
+	host := strings.TrimPrefix(mock.URL, "http://")
+	body := `{"links": ["` + host + `/ok", "` + mock.URL + `/ok", "HTTP://` + host + `/ok#x", "not a url", "http://"]}`
+
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ожидали статус 200, получили %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.ResponseLinks
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal("не удалось декодировать ответ:", err)
+	}
+
+	// прежнее поле links - по адресам из запроса, как их прислал клиент
+	for _, url := range []string{host + "/ok", mock.URL + "/ok", "HTTP://" + host + "/ok#x"} {
+		if resp.Links[url] != api.AvailableStatus {
+			t.Errorf("ожидали доступную ссылку %s, получили %v", url, resp.Links)
+		}
+	}
+	if len(resp.Links) != 5 || resp.Links["not a url"] != api.NotAvailableStatus {
+		t.Errorf("ожидали пять адресов из запроса, некорректные - not available, получили %v", resp.Links)
+	}
+
+	// проверка одна, под нормализованным адресом, а некорректные адреса - в состоянии invalid
+	if len(resp.States) != 3 || resp.States[mock.URL+"/ok"] != data.UpStatus ||
+		resp.States["not a url"] != data.InvalidStatus || resp.States["http://"] != data.InvalidStatus {
+		t.Errorf("ожидали одну проверку %s/ok и два invalid, получили %v", mock.URL, resp.States)
+	}
+	if len(resp.Normalized) != 2 || resp.Normalized[host+"/ok"] != mock.URL+"/ok" {
+		t.Errorf("ожидали два изменённых адреса, получили %v", resp.Normalized)
+	}
+	if len(resp.Invalid) != 2 || resp.Invalid["not a url"] == "" {
+		t.Errorf("ожидали два некорректных адреса с причинами, получили %v", resp.Invalid)
+	}
+
+	// только некорректные адреса
+	rec = httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(`{"links": ["not a url"]}`)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"invalid"`) {
+		t.Errorf("ожидали 400 с причинами, получили %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLegacyLinksByRequestURLs(t *testing.T) {
+	mock := startMockServer()
+	defer mock.Close()
+
+	server.Srv.Mu.Lock()
+	server.Srv.IsShutdown = false
+	server.Srv.Mu.Unlock()
+
+	host := strings.TrimPrefix(mock.URL, "http://")
+	sent := []string{"HTTP://" + host + "/ok#x", host + "/ok", "not a url"}
+	body := `{"links": ["` + strings.Join(sent, `", "`) + `"]}`
+
+	h := api.NewHandlers(data.NewStorage(), testEngine)
+
+	// ключи прежнего поля links - адреса из запроса, как бы ни был получен набор
+	check := func(mode string, links map[string]string) {
+		t.Helper()
+		if len(links) != len(sent) || links[sent[0]] != api.AvailableStatus ||
+			links[sent[1]] != api.AvailableStatus || links["not a url"] != api.NotAvailableStatus {
+			t.Errorf("%s: ожидали ключи %v, получили %v", mode, sent, links)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check", bytes.NewBufferString(body)))
+	var done api.ResponseLinks
+	json.NewDecoder(rec.Body).Decode(&done)
+	check("синхронно", done.Links)
+
+	rec = httptest.NewRecorder()
+	h.CheckPostHandler(rec, httptest.NewRequest(http.MethodPost, "/api/check?async=true", bytes.NewBufferString(body)))
+	var job api.ResponseJob
+	json.NewDecoder(rec.Body).Decode(&job)
+
+	// ждём завершения асинхронной проверки, набор уже из хранилища
+	for _, num := range []int{done.LinksNum, job.LinksNum} {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			req := httptest.NewRequest(http.MethodGet, "/api/check/"+strconv.Itoa(num), nil)
+			req.SetPathValue("links_num", strconv.Itoa(num))
+			rec := httptest.NewRecorder()
+			h.CheckGetHandler(rec, req)
+
+			var resp api.ResponseJob
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.State == api.JobDone || time.Now().After(deadline) {
+				check("по номеру "+strconv.Itoa(num), resp.Links)
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
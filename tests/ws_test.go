@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"verifi-server/data"
+)
+
+// startWebSocketServer websocket сервер, который здоровается и возвращает присланные сообщения;
+// по пути /plain отвечает обычной страницей без Upgrade
+func startWebSocketServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		writeServerFrame(rw.Writer, []byte("hello"))
+		rw.Flush()
+
+		for {
+			opcode, payload, err := readClientFrame(rw.Reader)
+			if err != nil || opcode == 0x8 {
+				return
+			}
+			writeServerFrame(rw.Writer, append([]byte("echo: "), payload...))
+			rw.Flush()
+		}
+	}))
+}
+
+// writeServerFrame короткий незамаскированный текстовый кадр сервера
+func writeServerFrame(w *bufio.Writer, payload []byte) {
+	w.Write([]byte{0x81, byte(len(payload))})
+	w.Write(payload)
+}
+
+// readClientFrame короткий замаскированный кадр клиента
+func readClientFrame(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= header[2+i%4]
+	}
+
+	return header[0] & 0x0F, payload, nil
+}
+
+func TestCheckWebSocket(t *testing.T) {
+	server := startWebSocketServer(t)
+	defer server.Close()
+
+	addr := "ws://" + strings.TrimPrefix(server.URL, "http://")
+	short := &data.Policy{Timeout: data.Duration(200 * time.Millisecond)}
+
+	tests := []struct {
+		name      string
+		link      data.Link
+		available bool
+		kind      string
+		banner    string
+	}{
+		{"рукопожатие", data.Link{Url: addr + "/socket"}, true, "", ""},
+		{"приветствие совпало", data.Link{Url: addr + "/socket", Expect: "^hello$"}, true, "", "hello"},
+		{"ответ на сообщение", data.Link{Url: addr + "/socket", Send: "ping", Expect: "^echo: ping$"}, true, "", "echo: ping"},
+		{"ответ не совпал", data.Link{Url: addr + "/socket", Expect: "^bye$", Policy: short}, false, data.ErrorKindContent, "hello"},
+		{"без upgrade", data.Link{Url: addr + "/plain"}, false, data.ErrorKindStatus, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testEngine.CheckLink(tt.link)
+
+			if (res.Status == data.AvailableStatus) != tt.available || res.ErrorKind != tt.kind {
+				t.Errorf("ожидали доступность %v (%q), получили %+v", tt.available, tt.kind, res)
+			}
+			if res.Banner != tt.banner {
+				t.Errorf("ожидали ответ %q, получили %q", tt.banner, res.Banner)
+			}
+			if res.Protocol != "ws" {
+				t.Errorf("ожидали протокол ws, получили %q", res.Protocol)
+			}
+		})
+	}
+}